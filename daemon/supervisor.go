@@ -0,0 +1,486 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const envWorkerName = "GLIB_WORKER_NAME" // 工作进程名称
+
+const (
+	defaultReadyTimeout = time.Second * 30       // 默认就绪超时
+	defaultStopTimeout  = time.Second * 10       // 默认停止超时
+	probeInterval       = time.Millisecond * 100 // 就绪探测间隔
+)
+
+var errStopped = errors.New("supervisor stopped")
+
+// Probe reports whether a worker is ready, a nil error means ready.
+type Probe func() error
+
+// ProbeTCP returns a probe which is ready once addr accepts tcp connections.
+func ProbeTCP(addr string) Probe {
+	return func() error {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
+
+// ProbeFile returns a probe which is ready once filename exists.
+func ProbeFile(filename string) Probe {
+	return func() (err error) {
+		_, err = os.Stat(filename)
+		return
+	}
+}
+
+// Worker describes a named process managed by a Supervisor.
+type Worker struct {
+	Name     string        // 名称，唯一
+	Path     string        // 可执行文件，为空时启动当前程序
+	Args     []string      // 启动参数，不含程序名
+	Env      []string      // 追加的环境变量
+	After    []string      // 弱依赖：只约束启动顺序，依赖未就绪也会启动
+	Requires []string      // 强依赖：依赖未就绪时不启动
+	Ready    Probe         // 就绪探针，为空时进程启动即就绪
+	Timeout  time.Duration // 就绪及停止等待超时
+	Always   bool          // 退出后是否重启
+}
+
+type worker struct {
+	Worker
+	proc   *os.Process
+	ready  bool
+	exited chan struct{}
+}
+
+// Supervisor starts workers in dependency order, restarts them when
+// required and stops them in reverse order.
+type Supervisor struct {
+	mutex   sync.Mutex
+	order   []*worker
+	workers map[string]*worker
+	stopped bool
+	wait    sync.WaitGroup
+}
+
+// NewSupervisor validates the workers and resolves their start order.
+func NewSupervisor(workers ...Worker) (_ *Supervisor, err error) {
+	var s = &Supervisor{
+		workers: make(map[string]*worker, len(workers)),
+	}
+
+	for _, w := range workers {
+		if w.Name == "" {
+			return nil, errors.New("worker name is empty")
+		}
+
+		if _, exists := s.workers[w.Name]; exists {
+			return nil, fmt.Errorf("worker %s is duplicated", w.Name)
+		}
+
+		s.workers[w.Name] = &worker{Worker: w}
+	}
+
+	var names []string
+	if names, err = sortWorkers(workers); err != nil {
+		return
+	}
+
+	for _, name := range names {
+		s.order = append(s.order, s.workers[name])
+	}
+
+	return s, nil
+}
+
+// sortWorkers 按依赖关系拓扑排序，无依赖关系时保持声明顺序
+func sortWorkers(workers []Worker) (names []string, err error) {
+	var (
+		degree     = make(map[string]int, len(workers))
+		dependents = make(map[string][]string, len(workers))
+	)
+
+	for _, w := range workers {
+		degree[w.Name] = 0
+	}
+
+	for _, w := range workers {
+		var deps = append(slices.Clone(w.After), w.Requires...)
+
+		slices.Sort(deps)
+		for _, dep := range slices.Compact(deps) {
+			if _, exists := degree[dep]; !exists {
+				return nil, fmt.Errorf("worker %s depends on unknown worker %s", w.Name, dep)
+			}
+
+			if dep == w.Name {
+				return nil, fmt.Errorf("worker %s depends on itself", w.Name)
+			}
+
+			degree[w.Name]++
+			dependents[dep] = append(dependents[dep], w.Name)
+		}
+	}
+
+	var done = make(map[string]bool, len(workers))
+	for len(names) < len(workers) {
+		var found bool
+		for _, w := range workers {
+			if done[w.Name] || degree[w.Name] > 0 {
+				continue
+			}
+
+			for _, name := range dependents[w.Name] {
+				degree[name]--
+			}
+
+			done[w.Name] = true
+			names = append(names, w.Name)
+			found = true
+
+			break
+		}
+
+		// 剩余节点存在环
+		if !found {
+			var cycle []string
+			for _, w := range workers {
+				if !done[w.Name] {
+					cycle = append(cycle, w.Name)
+				}
+			}
+
+			return nil, fmt.Errorf("workers have cyclic dependencies: %v", cycle)
+		}
+	}
+
+	return
+}
+
+func (s *Supervisor) spawn(w *worker) (err error) {
+	var (
+		name = w.Path
+		envs = append(os.Environ(), w.Env...)
+	)
+
+	if name == "" {
+		if name, err = os.Executable(); err != nil {
+			return
+		}
+	}
+
+	envs = append(envs, fmt.Sprintf("%s=%s", envWorkerName, w.Name))
+
+	var attr = os.ProcAttr{
+		Env: envs,
+		Files: []*os.File{
+			os.Stdin,
+			os.Stdout,
+			os.Stderr,
+		},
+	}
+
+	proc, err := os.StartProcess(name, append([]string{name}, w.Args...), &attr)
+	if err != nil {
+		return
+	}
+
+	w.proc = proc
+	w.ready = false
+	w.exited = make(chan struct{})
+
+	return
+}
+
+// waitReady 等待就绪探针成功，进程退出或超时则失败
+func (s *Supervisor) waitReady(w *worker, exited chan struct{}) (err error) {
+	if w.Ready == nil {
+		return
+	}
+
+	var timeout = w.Timeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+
+	var (
+		ticker   = time.NewTicker(probeInterval)
+		deadline = time.After(timeout)
+	)
+	defer ticker.Stop()
+
+	for {
+		if err = w.Ready(); err == nil {
+			return
+		}
+
+		select {
+		case <-exited:
+			return fmt.Errorf("worker %s exited before ready", w.Name)
+		case <-deadline:
+			return fmt.Errorf("worker %s is not ready after %s: %w", w.Name, timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// start 启动进程并等待就绪，已停止时不再启动，避免重启的进程在Stop后遗留
+func (s *Supervisor) start(w *worker) (err error) {
+	s.mutex.Lock()
+	if s.stopped {
+		s.mutex.Unlock()
+		return errStopped
+	}
+
+	for _, dep := range w.Requires {
+		if !s.workers[dep].ready {
+			s.mutex.Unlock()
+			return fmt.Errorf("worker %s requires %s, which is not ready", w.Name, dep)
+		}
+	}
+
+	if err = s.spawn(w); err != nil {
+		s.mutex.Unlock()
+		return
+	}
+
+	var (
+		proc   = w.proc
+		exited = w.exited
+	)
+	s.mutex.Unlock()
+
+	s.wait.Add(1)
+	go s.monitor(w, proc, exited)
+
+	if err = s.waitReady(w, exited); err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if w.proc == proc {
+		w.ready = true
+	}
+	s.mutex.Unlock()
+
+	return
+}
+
+// monitor 回收退出的进程，按需重启
+func (s *Supervisor) monitor(w *worker, proc *os.Process, exited chan struct{}) {
+	defer s.wait.Done()
+
+	state, err := proc.Wait()
+	if err != nil {
+		log.Println(err)
+	} else if !state.Success() {
+		log.Printf("worker %s exited: %s", w.Name, state)
+	}
+
+	s.mutex.Lock()
+	w.ready = false
+	close(exited)
+
+	var restart = w.Always && !s.stopped
+	s.mutex.Unlock()
+
+	if !restart {
+		return
+	}
+
+	for {
+		time.Sleep(time.Second)
+
+		if s.isStopped() {
+			return
+		}
+
+		if err = s.start(w); err == nil || errors.Is(err, errStopped) {
+			return
+		}
+
+		log.Println(err)
+
+		// 已启动但未就绪，由新进程的monitor负责
+		if s.isAlive(w) {
+			return
+		}
+	}
+}
+
+func (s *Supervisor) isAlive(w *worker) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if w.exited == nil {
+		return false
+	}
+
+	select {
+	case <-w.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *Supervisor) isStopped() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.stopped
+}
+
+// Start starts all workers in dependency order. Every worker waits for
+// the readiness of its dependencies, a worker whose required dependency
+// is not ready is not started. Start fails once Stop has been called.
+func (s *Supervisor) Start() (err error) {
+	var errs []error
+	for _, w := range s.order {
+		if err = s.start(w); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stop stops all workers in reverse dependency order.
+func (s *Supervisor) Stop() (err error) {
+	s.mutex.Lock()
+	s.stopped = true
+	s.mutex.Unlock()
+
+	var errs []error
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if err = s.stop(s.order[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Supervisor) stop(w *worker) (err error) {
+	s.mutex.Lock()
+	var (
+		proc   = w.proc
+		exited = w.exited
+	)
+	s.mutex.Unlock()
+
+	if proc == nil {
+		return
+	}
+
+	select {
+	case <-exited:
+		return
+	default:
+	}
+
+	if err = proc.Signal(syscall.SIGTERM); err != nil {
+		return
+	}
+
+	var timeout = w.Timeout
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
+	}
+
+	select {
+	case <-exited:
+		return
+	case <-time.After(timeout):
+	}
+
+	// 超时强制结束
+	if err = proc.Kill(); err != nil {
+		return
+	}
+
+	<-exited
+
+	return
+}
+
+// Wait waits until all workers exit and will not be restarted.
+func (s *Supervisor) Wait() {
+	s.wait.Wait()
+}
+
+// 工作进程
+func isWorker() bool {
+	return os.Getenv(envWorkerName) != ""
+}
+
+// WorkerName returns the name of the worker the current process runs as,
+// or an empty string when it is not started by a Supervisor.
+func WorkerName() string {
+	return os.Getenv(envWorkerName)
+}
+
+// Supervise runs the current process as a daemon supervising workers.
+// Workers without Path run the current program, which returns from
+// Supervise immediately and can use WorkerName to pick its role.
+// Params:
+//   - dup: Duplicate standard file descriptors.
+//   - workers: Workers to supervise.
+func Supervise(dup bool, workers ...Worker) (err error) {
+	// 1. 工作进程
+	if isWorker() {
+		return
+	}
+
+	// 2. 校验依赖
+	s, err := NewSupervisor(workers...)
+	if err != nil {
+		return
+	}
+
+	// 3. 守护进程
+	if err = daemon(dup); err != nil {
+		log.Println(err)
+	}
+
+	// 4. 按依赖顺序启动
+	if err = s.Start(); err != nil {
+		log.Println(err)
+	}
+
+	// 5. 收到信号后逆序停止
+	var signals = make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	var done = make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-signals:
+	case <-done:
+	}
+
+	if err = s.Stop(); err != nil {
+		log.Println(err)
+	}
+
+	exit()
+
+	return
+}
@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortWorkers(t *testing.T) {
+	var workers = []Worker{
+		{Name: "app", Requires: []string{"cache", "proxy"}},
+		{Name: "proxy", After: []string{"cache"}},
+		{Name: "cache"},
+		{Name: "metrics"},
+	}
+
+	names, err := sortWorkers(workers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(names, []string{"cache", "proxy", "app", "metrics"}) {
+		t.Fatal("sortWorkers order error:", names)
+	}
+}
+
+func TestSortWorkers_Error(t *testing.T) {
+	var cases = [][]Worker{
+		{{Name: "a", After: []string{"b"}}, {Name: "b", Requires: []string{"a"}}},
+		{{Name: "a", Requires: []string{"a"}}},
+		{{Name: "a", After: []string{"unknown"}}},
+	}
+
+	for _, workers := range cases {
+		if _, err := sortWorkers(workers); err == nil {
+			t.Fatal("sortWorkers should fail:", workers)
+		}
+	}
+}
+
+func TestSupervisor(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		ready = filepath.Join(dir, "cache.ready")
+	)
+
+	s, err := NewSupervisor(
+		Worker{
+			Name:     "app",
+			Path:     "/bin/sh",
+			Args:     []string{"-c", "test -f " + ready + " && exec sleep 10"},
+			Requires: []string{"cache"},
+		},
+		Worker{
+			Name:  "cache",
+			Path:  "/bin/sh",
+			Args:  []string{"-c", "sleep 0.2 && touch " + ready + " && exec sleep 10"},
+			Ready: ProbeFile(ready),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// app启动时cache已就绪，不会立即退出
+	time.Sleep(time.Millisecond * 200)
+	if !s.isAlive(s.workers["app"]) {
+		t.Fatal("app exited, cache was not ready before app started")
+	}
+
+	if err = s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Wait()
+
+	if _, err = os.Stat(ready); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSupervisor_StartAfterStop(t *testing.T) {
+	s, err := NewSupervisor(Worker{Name: "app", Path: "/bin/sh", Args: []string{"-c", "exec sleep 10"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stop之后的重启不再启动进程
+	var w = s.workers["app"]
+	if err = s.start(w); !errors.Is(err, errStopped) || w.proc != nil {
+		t.Fatal("start after stop should fail:", err, w.proc)
+	}
+}
@@ -0,0 +1,443 @@
+package embed
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// 块链：头块的NextOffset指向下个块的头，后续块带flagChained标志，存储的数据
+// 按顺序依次填满各块。压缩、加密及签名等标志只记录在头块中，各块的DataLen及
+// DataCRC32描述各自存放的部分。
+
+var (
+	errAtomicChain = errors.New("chained blocks do not support atomic writes")
+	errSlotLayout  = errors.New("data overlaps every slot, can not change the slots safely")
+)
+
+// segment 一段连续的存储区域
+type segment struct {
+	offset int64 // 文件偏移
+	size   int64 // 长度
+}
+
+// segments 按顺序拼接的存储区域，实现io.ReaderAt及io.WriterAt
+type segments struct {
+	file storage
+	list []segment
+}
+
+func (s segments) size() (size int64) {
+	for _, seg := range s.list {
+		size += seg.size
+	}
+
+	return
+}
+
+func (s segments) ReadAt(buf []byte, off int64) (n int, err error) {
+	for _, seg := range s.list {
+		if len(buf) == 0 {
+			return
+		}
+
+		if off >= seg.size {
+			off -= seg.size
+			continue
+		}
+
+		var m int
+		m, err = s.file.ReadAt(buf[:min(int64(len(buf)), seg.size-off)], seg.offset+off)
+		if n += m; err != nil {
+			return
+		}
+
+		buf, off = buf[m:], 0
+	}
+
+	if len(buf) > 0 {
+		err = io.EOF
+	}
+
+	return
+}
+
+func (s segments) WriteAt(data []byte, off int64) (n int, err error) {
+	for _, seg := range s.list {
+		if len(data) == 0 {
+			return
+		}
+
+		if off >= seg.size {
+			off -= seg.size
+			continue
+		}
+
+		var m int
+		m, err = s.file.WriteAt(data[:min(int64(len(data)), seg.size-off)], seg.offset+off)
+		if n += m; err != nil {
+			return
+		}
+
+		data, off = data[m:], 0
+	}
+
+	if len(data) > 0 {
+		err = errors.New("data too large")
+	}
+
+	return
+}
+
+// linkChains 按NextOffset组装块链，后续块不单独返回，链不完整的头块视为损坏丢弃，
+// 失去头块的后续块作为普通块返回
+func linkChains(headers []Header) (heads []Header, chains [][]Header) {
+	var (
+		index   = make(map[int64]int, len(headers)) // 头偏移 -> 下标
+		members = make(map[int]bool)
+		broken  = make(map[int]bool)
+		links   = make(map[int][]Header)
+	)
+
+	for i, h := range headers {
+		index[h.Offset-headerSize] = i
+	}
+
+	for i, h := range headers {
+		if h.chained() || h.NextOffset == 0 {
+			continue
+		}
+
+		var (
+			chain []Header
+			seen  = make(map[int]bool)
+		)
+		for next := h.NextOffset; next != 0; {
+			j, ok := index[int64(next)]
+			if !ok || j == i || seen[j] || members[j] || !headers[j].chained() {
+				broken[i] = true
+				break
+			}
+
+			seen[j] = true
+			chain = append(chain, headers[j])
+			next = headers[j].NextOffset
+		}
+
+		if broken[i] {
+			continue
+		}
+
+		for j := range seen {
+			members[j] = true
+		}
+		links[i] = chain
+	}
+
+	for i, h := range headers {
+		if members[i] || broken[i] {
+			continue
+		}
+
+		heads = append(heads, h)
+		chains = append(chains, links[i])
+	}
+
+	return
+}
+
+// Chained reports whether the block spans a chain of reserved blocks.
+func (b *Block) Chained() bool {
+	defer b.rlock()()
+
+	return b.chained()
+}
+
+func (b Block) chained() bool {
+	return len(b.chain) > 0
+}
+
+// link 按容量依次划分存储的数据，更新并写入后续块的头，头块的数据长度、
+// crc32及链接由调用方写入
+func (b *Block) link(h *Header, stored uint32) (chain []Header, err error) {
+	var (
+		headers = append([]Header{*h}, b.chain...)
+		remain  = stored
+	)
+
+	for i := range headers {
+		var size = min(remain, headers[i].capacity())
+		remain -= size
+
+		headers[i].DataLen = size
+		headers[i].NextOffset = 0
+		if i < len(headers)-1 {
+			headers[i].NextOffset = uint32(headers[i+1].Offset - headerSize)
+		}
+
+		// 流式计算数据crc32
+		var hash = crc32.NewIEEE()
+		if _, err = io.Copy(hash, io.NewSectionReader(b.file, headers[i].Offset, int64(size))); err != nil {
+			return
+		}
+		headers[i].DataCRC32 = hash.Sum32()
+	}
+
+	if remain > 0 {
+		return nil, errors.New("data too large")
+	}
+
+	// 数据落盘后再写入后续块的头
+	if err = b.file.Sync(); err != nil {
+		return
+	}
+
+	chain = headers[1:]
+	for i := range chain {
+		chain[i].Flags = flagChained
+		chain[i].RawLen = 0
+		chain[i].UpdateTime = h.UpdateTime
+		if chain[i].CreateTime == 0 {
+			chain[i].CreateTime = h.UpdateTime
+		}
+
+		if chain[i].CRC32, err = chain[i].checksum(); err != nil {
+			return
+		}
+	}
+
+	if err = writeHeaders(b.file, chain); err != nil {
+		return
+	}
+
+	h.DataLen = headers[0].DataLen
+	h.DataCRC32 = headers[0].DataCRC32
+	h.NextOffset = headers[0].NextOffset
+
+	return
+}
+
+// writeHeader 计算头crc32并写入文件
+func (b *Block) writeHeader(h *Header) (err error) {
+	var data []byte
+	if h.CRC32, err = h.checksum(); err != nil {
+		return
+	}
+
+	if data, err = h.Encode(); err != nil {
+		return
+	}
+
+	if _, err = b.file.WriteAt(data, h.Offset-headerSize); err != nil {
+		return
+	}
+
+	return b.file.Sync()
+}
+
+// newChain 校验并组装块链，仅修改内存中的头块
+func newChain(blocks []*Block) (head Block, err error) {
+	if len(blocks) < 2 {
+		return head, errors.New("chain needs at least two blocks")
+	}
+
+	var (
+		total   uint64
+		offsets = make(map[int64]bool, len(blocks))
+	)
+
+	head = *blocks[0]
+	head.chain = nil
+	for i, b := range blocks {
+		if b.chained() {
+			return head, fmt.Errorf("block %d is already chained", i)
+		}
+
+		if b.header.slotted() || b.atomic {
+			return head, errAtomicChain
+		}
+
+		if offsets[b.header.Offset] {
+			return head, fmt.Errorf("block %d is duplicated", i)
+		}
+		offsets[b.header.Offset] = true
+
+		if b.header.Offset-headerSize > math.MaxUint32 {
+			return head, fmt.Errorf("block %d is out of chain range", i)
+		}
+
+		if total += uint64(b.header.capacity()); total > math.MaxUint32 {
+			return head, errors.New("chain capacity too large")
+		}
+
+		// 后续块原有的数据丢弃
+		if i > 0 {
+			var h = b.header
+			h.DataLen = 0
+			head.chain = append(head.chain, h)
+		}
+	}
+
+	return
+}
+
+// Chain links blocks into one logical block in the given order, reads and
+// writes of the returned head block stream across all of them, Blocks
+// presents the chain as the head block only. The data of the head block is
+// kept, the data of the other blocks is discarded.
+func (e *Embed) Chain(blocks ...*Block) (_ *Block, err error) {
+	current, err := e.Blocks()
+	if err != nil {
+		return
+	}
+
+	// 以文件中的最新状态为准，避免重复链接
+	var latest = make([]*Block, len(blocks))
+	for i, b := range blocks {
+		var index = slices.IndexFunc(current, func(c Block) bool {
+			return c.header.Offset == b.header.Offset
+		})
+		if index == -1 {
+			return nil, fmt.Errorf("block %d is not available", i)
+		}
+
+		var clone = *b
+		clone.header, clone.chain = current[index].header, current[index].chain
+		latest[i] = &clone
+	}
+
+	head, err := newChain(latest)
+	if err != nil {
+		return
+	}
+
+	// 先写后续块，头块写入后链接生效
+	var h = head.header
+	h.Flags &^= flagChained
+	h.UpdateTime = time.Now().Unix()
+	if head.chain, err = head.link(&h, head.header.DataLen); err != nil {
+		return
+	}
+
+	if err = head.writeHeader(&h); err != nil {
+		return
+	}
+
+	head.header = h
+
+	return &head, nil
+}
+
+// Unchain splits a chained block back into independent empty blocks.
+func (b *Block) Unchain() (err error) {
+	defer b.wlock()()
+
+	if !b.chained() {
+		return errors.New("block is not chained")
+	}
+
+	// 先断开头块，失去头块的后续块即为普通块
+	var headers = append([]Header{b.header}, b.chain...)
+	for i := range headers {
+		var h = &headers[i]
+
+		h.Flags, h.NextOffset = h.Flags&(flagExtSize|flagVersion), 0
+		h.DataLen, h.DataCRC32, h.RawLen = 0, 0, 0
+		h.UpdateTime = time.Now().Unix()
+
+		if err = b.writeHeader(h); err != nil {
+			return
+		}
+	}
+
+	b.header, b.chain = headers[0], nil
+	b.compress, b.encrypt = CompressNone, EncryptNone
+
+	return
+}
+
+// MallocChain allocates the blocks reserved by sizes as one chained block
+// in the given order. Blocks which are not chained yet, for example in a
+// freshly built executable, are linked by the first write.
+func MallocChain(sizes ...Size) (_ *Block, err error) {
+	mallocLock.Lock()
+	defer mallocLock.Unlock()
+
+	var hashes = make([]string, len(sizes))
+	for i, size := range sizes {
+		if len(size) < headerSize {
+			return nil, errors.New("invalid size")
+		}
+
+		if hashes[i] = md5sum(stringBytes(string(size))); malloc[hashes[i]] != nil {
+			return nil, errors.New("block already malloced")
+		}
+	}
+
+	if len(sizes) < 2 {
+		return nil, errors.New("chain needs at least two blocks")
+	}
+
+	blocks, err := Blocks()
+	if err != nil {
+		return
+	}
+
+	head, err := findBlock(blocks, sizes[0])
+	if err != nil {
+		return
+	}
+
+	// 已链接的块链需与sizes一致
+	if head.chained() {
+		if len(head.chain) != len(sizes)-1 {
+			return nil, errors.New("block chain mismatch")
+		}
+
+		for i, h := range head.chain {
+			var ok bool
+			if ok, err = matchSize(head.file, h, sizes[i+1]); err != nil {
+				return
+			}
+
+			if !ok {
+				return nil, errors.New("block chain mismatch")
+			}
+		}
+	} else {
+		var members = []*Block{head}
+		for _, size := range sizes[1:] {
+			var b *Block
+			if b, err = findBlock(blocks, size); err != nil {
+				return
+			}
+			members = append(members, b)
+		}
+
+		var chain Block
+		if chain, err = newChain(members); err != nil {
+			return
+		}
+		head = &chain
+	}
+
+	for _, hash := range hashes {
+		malloc[hash] = head
+	}
+
+	return head, nil
+}
+
+// MustMallocChain is like MallocChain but panics on error.
+func MustMallocChain(sizes ...Size) *Block {
+	block, err := MallocChain(sizes...)
+	if err != nil {
+		panic(err)
+	}
+
+	return block
+}
@@ -0,0 +1,131 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEmbed_Chain(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1", Size1KB+"2", Size2KB+"3", Size1KB+"4")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = blocks[0].Write([]byte("head")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 链接0、2、1，保留头块数据
+	head, err := emd.Chain(&blocks[0], &blocks[2], &blocks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !head.Chained() || head.Cap() != 4096 || readString(t, *head) != "head" {
+		t.Fatal("chain error:", head)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 2 || blocks[0].Cap() != 4096 || blocks[1].Chained() {
+		t.Fatal("chain blocks error:", err, blocks)
+	}
+
+	// 跨块流式写入
+	var (
+		data   = bytes.Repeat([]byte("0123456789"), 350)
+		writer = blocks[0].NewWriter()
+	)
+	if _, err = io.CopyBuffer(writer, bytes.NewReader(data), make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 2 || blocks[0].Len() != uint32(len(data)) {
+		t.Fatal("chain write error:", err, blocks)
+	}
+
+	result, err := io.ReadAll(blocks[0].NewReader())
+	if err != nil || !bytes.Equal(result, data) {
+		t.Fatal("chain read error:", err)
+	}
+
+	var buf = make([]byte, 20)
+	if _, err = blocks[0].ReadAt(buf, 1020); err != nil || !bytes.Equal(buf, data[1020:1040]) {
+		t.Fatal("chain read at error:", err, string(buf))
+	}
+
+	// 超出容量
+	if _, err = blocks[0].Write(make([]byte, 4097)); err == nil {
+		t.Fatal("write over chain capacity should fail")
+	}
+
+	// 压缩及加密的数据同样跨块存储
+	var random = make([]byte, 3000)
+	for i := range random {
+		random[i] = byte(i * i >> 3)
+	}
+
+	_ = blocks[0].SetEncryption(EncryptAESGCM)
+	blocks[0].SetKey(KeyFunc(func() ([]byte, error) { return make([]byte, 32), nil }))
+	if _, err = blocks[0].Write(random); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks[0].StoredLen() <= 2048 || !bytes.Equal([]byte(readString(t, blocks[0])), random) {
+		t.Fatal("chain encryption error:", blocks[0])
+	}
+
+	// 拆分后各块独立且为空
+	if err = blocks[0].Unchain(); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 4 {
+		t.Fatal("unchain error:", err, blocks)
+	}
+
+	for _, b := range blocks {
+		if b.Chained() || b.Len() != 0 || b.Cap() == 0 {
+			t.Fatal("unchain block error:", b)
+		}
+	}
+}
+
+func TestEmbed_ChainBroken(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1", Size1KB+"2", Size1KB+"3")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = emd.Chain(&blocks[0], &blocks[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = emd.Chain(&blocks[1], &blocks[2]); err == nil {
+		t.Fatal("chain chained block should fail")
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 2 {
+		t.Fatal("chain blocks error:", err, blocks)
+	}
+
+	if _, err = blocks[0].Write(bytes.Repeat([]byte("x"), 1500)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 后续块损坏时丢弃整个链
+	if _, err = emd.file.WriteAt([]byte("broken"), blocks[0].chain[0].Offset); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || blocks[0].Cap() != 1024 {
+		t.Fatal("broken chain error:", err, blocks)
+	}
+}
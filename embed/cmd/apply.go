@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/zooyer/golib/embed"
+)
+
+// source 清单中块数据的来源，只能指定一种
+type source struct {
+	File   string `json:"file,omitempty"`   // 文件，相对路径基于清单所在目录
+	String string `json:"string,omitempty"` // 字符串
+	Env    string `json:"env,omitempty"`    // 环境变量
+}
+
+// entry 校验后待写入的块
+type entry struct {
+	key  string
+	id   int
+	data []byte
+}
+
+// readManifest 读取清单，-为标准输入
+func readManifest(filename string) (manifest map[string]source, dir string, err error) {
+	var reader io.Reader = os.Stdin
+	if filename != stdio {
+		var file *os.File
+		if file, err = os.Open(filename); err != nil {
+			return
+		}
+		defer file.Close()
+
+		reader, dir = file, filepath.Dir(filename)
+	}
+
+	var decoder = json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return
+}
+
+// load 读取来源的数据
+func (s source) load(dir string) (data []byte, err error) {
+	var count int
+	for _, v := range []string{s.File, s.String, s.Env} {
+		if v != "" {
+			count++
+		}
+	}
+
+	if count != 1 {
+		return nil, errors.New("exactly one of file, string or env is required")
+	}
+
+	switch {
+	case s.File != "":
+		var filename = s.File
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+
+		return os.ReadFile(filename)
+	case s.Env != "":
+		value, exists := os.LookupEnv(s.Env)
+		if !exists {
+			return nil, fmt.Errorf("environment variable %s not set", s.Env)
+		}
+
+		return []byte(value), nil
+	}
+
+	return []byte(s.String), nil
+}
+
+// resolve 按编号或名称查找块
+func resolve(blocks []embed.Block, key string) (int, error) {
+	if isNumber(key) && key != "" {
+		id, err := strconv.Atoi(key)
+		if err != nil || id >= len(blocks) {
+			return -1, fmt.Errorf("block %s not found", key)
+		}
+
+		return id, nil
+	}
+
+	for id, block := range blocks {
+		if block.Name() == key {
+			return id, nil
+		}
+	}
+
+	return -1, fmt.Errorf("block %s not found", key)
+}
+
+// validate 校验整个清单：块存在且不重复、来源可读、数据不超过容量
+func validate(blocks []embed.Block, manifest map[string]source, dir string) (entries []entry, err error) {
+	var (
+		keys  = make([]string, 0, len(manifest))
+		seen  = make(map[int]string)
+		errs  []error
+		fails = func(key string, err error) {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	)
+	for key := range manifest {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		id, err := resolve(blocks, key)
+		if err != nil {
+			fails(key, err)
+			continue
+		}
+
+		if other, exists := seen[id]; exists {
+			fails(key, fmt.Errorf("block %d is also set by %s", id, other))
+			continue
+		}
+		seen[id] = key
+
+		data, err := manifest[key].load(dir)
+		if err != nil {
+			fails(key, err)
+			continue
+		}
+
+		if len(data) == 0 {
+			fails(key, errors.New("empty data"))
+			continue
+		}
+
+		// 按写入时的设置校验，原子写入使用槽的容量
+		var block = blocks[id]
+		if err = setupBlock(&block); err != nil {
+			fails(key, err)
+			continue
+		}
+
+		size, err := storedSize(bytes.NewReader(data))
+		if err != nil {
+			fails(key, err)
+			continue
+		}
+
+		if err = block.CheckWrite(uint32(min(size, math.MaxUint32))); err != nil {
+			fails(key, err)
+			continue
+		}
+
+		entries = append(entries, entry{key: key, id: id, data: data})
+	}
+
+	return entries, errors.Join(errs...)
+}
+
+// applyManifest 校验整个清单后一次写入所有块，写入副本后原子替换文件
+func applyManifest(file, filename string) {
+	manifest, dir, err := readManifest(filename)
+	if err != nil {
+		fmt.Printf("Error reading manifest %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	emd, blocks := openBlocks(file)
+
+	entries, err := validate(blocks, manifest, dir)
+	if err != nil {
+		fmt.Printf("Error validating manifest %s:\n%s\n", filename, err)
+		os.Exit(1)
+	}
+
+	err = emd.Update(func(e *embed.Embed) (err error) {
+		blocks, err := e.Blocks()
+		if err != nil {
+			return
+		}
+
+		for _, entry := range entries {
+			var block = &blocks[entry.id]
+			block.SetKey(key)
+
+			if err = setupBlock(block); err != nil {
+				return
+			}
+
+			if _, err = block.Write(entry.data); err != nil {
+				return fmt.Errorf("%s: %w", entry.key, err)
+			}
+		}
+
+		return
+	})
+	if err != nil {
+		fmt.Printf("Error applying manifest %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("Block %s (%d): %d bytes\n", entry.key, entry.id, len(entry.data))
+	}
+
+	fmt.Printf("Apply manifest %s successful.\n", filename)
+}
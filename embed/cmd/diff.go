@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zooyer/golib/embed"
+)
+
+const maxDiffLines = 4 * 1024 * 1024 // 行diff的最大计算量（行数乘积）
+
+// pairName 块对的名称
+func pairName(pair embed.BlockPair) string {
+	var id = func(id int) string {
+		if id == -1 {
+			return "-"
+		}
+
+		return fmt.Sprint(id)
+	}
+
+	var name = fmt.Sprintf("%s -> %s", id(pair.Old), id(pair.New))
+	if pair.Name != "" {
+		name = pair.Name + " (" + name + ")"
+	}
+
+	return name
+}
+
+// compare 打印两个值，相同时只打印一个
+func compare(name string, a, b any) {
+	if a == b {
+		fmt.Printf("  %s\t%v\n", name, a)
+	} else {
+		fmt.Printf("  %s\t%v -> %v\n", name, a, b)
+	}
+}
+
+// isText 数据为不含NUL的UTF-8文本
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) == -1
+}
+
+// diffLines 基于最长公共子序列打印行diff
+func diffLines(a, b []string) {
+	var lcs = make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Printf("    %s\n", a[i])
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Printf("  - %s\n", a[i])
+			i++
+		default:
+			fmt.Printf("  + %s\n", b[j])
+			j++
+		}
+	}
+}
+
+// splitLines 按行分割，空数据没有行
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// diffContent 比较块内容，文本打印行diff
+func diffContent(a, b []byte) {
+	if bytes.Equal(a, b) {
+		fmt.Println("  content identical")
+		return
+	}
+
+	if !isText(a) || !isText(b) {
+		fmt.Println("  binary content differs")
+		return
+	}
+
+	var linesA, linesB = splitLines(a), splitLines(b)
+	if len(linesA)*len(linesB) > maxDiffLines {
+		fmt.Println("  text content differs, too large to diff")
+		return
+	}
+
+	diffLines(linesA, linesB)
+}
+
+// readContent 读取块的原始数据
+func readContent(block embed.Block) (data []byte, err error) {
+	return io.ReadAll(block.NewReader())
+}
+
+func diffFiles(fileA, fileB string) {
+	_, blocksA := openBlocks(fileA)
+	_, blocksB := openBlocks(fileB)
+
+	var differs bool
+	for _, pair := range embed.PairBlocks(blocksA, blocksB) {
+		fmt.Printf("Block %s:\n", pairName(pair))
+
+		if pair.Old == -1 || pair.New == -1 {
+			var file = fileA
+			if pair.Old == -1 {
+				file = fileB
+			}
+
+			fmt.Printf("  only in %s\n\n", file)
+			differs = true
+			continue
+		}
+
+		var a, b = blocksA[pair.Old], blocksB[pair.New]
+		compare("cap", a.Cap(), b.Cap())
+		compare("len", a.Len(), b.Len())
+
+		dataA, errA := readContent(a)
+		dataB, errB := readContent(b)
+		if err := errors.Join(errA, errB); err != nil {
+			fmt.Printf("  error reading content: %s\n\n", err)
+			differs = true
+			continue
+		}
+
+		compare("crc32", fmt.Sprintf("%08x", crc32.ChecksumIEEE(dataA)), fmt.Sprintf("%08x", crc32.ChecksumIEEE(dataB)))
+		diffContent(dataA, dataB)
+		fmt.Println()
+
+		if !bytes.Equal(dataA, dataB) {
+			differs = true
+		}
+	}
+
+	if differs {
+		os.Exit(1)
+	}
+}
+
+// same 两个路径是否为同一文件
+func same(fileA, fileB string) bool {
+	infoA, errA := os.Stat(fileA)
+	infoB, errB := os.Stat(fileB)
+
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+func transplantFiles(oldFile, newFile string) {
+	// 同一文件的共享锁与排它锁互斥
+	if same(oldFile, newFile) {
+		fmt.Printf("Error transplanting blocks: %s and %s are the same file\n", oldFile, newFile)
+		os.Exit(1)
+	}
+
+	var (
+		old = openLocked(oldFile, false)
+		emd = openLocked(newFile, true)
+	)
+
+	results, err := emd.Transplant(old)
+	if err != nil {
+		fmt.Printf("Error transplanting blocks: %s\n", err)
+		os.Exit(1)
+	}
+
+	var failed bool
+	for _, r := range results {
+		switch {
+		case errors.Is(r.Err, embed.ErrNoMatch) && r.New == -1:
+			fmt.Printf("Block %s: no match in %s\n", pairName(r.BlockPair), newFile)
+			failed = true
+		case errors.Is(r.Err, embed.ErrNoMatch):
+			fmt.Printf("Block %s: no match in %s, kept\n", pairName(r.BlockPair), oldFile)
+		case r.Err != nil:
+			fmt.Printf("Block %s: %s\n", pairName(r.BlockPair), r.Err)
+			failed = true
+		case r.Len == 0:
+			fmt.Printf("Block %s: empty, skipped\n", pairName(r.BlockPair))
+		default:
+			fmt.Printf("Block %s: %d bytes transplanted\n", pairName(r.BlockPair), r.Len)
+		}
+	}
+
+	if failed {
+		fmt.Printf("Transplant blocks incomplete.\n")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Transplant blocks successful.\n")
+}
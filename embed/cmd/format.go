@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zooyer/golib/embed"
+)
+
+// 输出格式
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatTable = "table"
+)
+
+var format = formatText // 输出格式
+
+func parseFormat(name string) {
+	switch name {
+	case formatText, formatJSON, formatTable:
+		format = name
+	default:
+		help("%s: '%s' is not a output format.", this, name)
+	}
+}
+
+func printJSON(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding JSON: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(data))
+}
+
+func printTable(header []string, rows [][]string) {
+	var writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	_ = writer.Flush()
+}
+
+// formatTime 格式化时间，零值为-
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// printBlocks 按输出格式打印块信息
+func printBlocks(blocks []embed.Block, ids []int) {
+	switch format {
+	case formatJSON:
+		var objects = make([]map[string]any, 0, len(ids))
+		for _, id := range ids {
+			var object map[string]any
+			if err := json.Unmarshal([]byte(blocks[id].String()), &object); err != nil {
+				fmt.Printf("Error encoding block %d: %s\n", id, err)
+				os.Exit(1)
+			}
+
+			object["ID"] = id
+			objects = append(objects, object)
+		}
+
+		printJSON(objects)
+	case formatTable:
+		var rows [][]string
+		for _, id := range ids {
+			var b = blocks[id]
+			rows = append(rows, []string{
+				fmt.Sprint(id), b.Name(), b.Section(), fmt.Sprint(b.Version()), fmt.Sprint(b.Cap()), fmt.Sprint(b.Len()), fmt.Sprint(b.StoredLen()),
+				b.Compression().String(), b.Encryption().String(), fmt.Sprint(b.Signed()), fmt.Sprint(b.Atomic()),
+				fmt.Sprint(b.Chained()), formatTime(b.ModTime()),
+			})
+		}
+
+		printTable([]string{"ID", "NAME", "SECTION", "VERSION", "CAP", "LEN", "STORED", "COMPRESS", "ENCRYPT", "SIGNED", "ATOMIC", "CHAINED", "UPDATED"}, rows)
+	default:
+		for _, id := range ids {
+			fmt.Printf("Block %d:\n", id)
+			fmt.Println(blocks[id].String())
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/zooyer/golib/embed"
+)
+
+// check 一项校验结果
+type check struct {
+	Target string // 校验的对象
+	Error  string `json:",omitempty"`
+}
+
+func printChecks(checks []check) {
+	switch format {
+	case formatJSON:
+		printJSON(checks)
+	case formatTable:
+		var rows [][]string
+		for _, c := range checks {
+			var status = "ok"
+			if c.Error != "" {
+				status = c.Error
+			}
+
+			rows = append(rows, []string{c.Target, status})
+		}
+
+		printTable([]string{"TARGET", "STATUS"}, rows)
+	default:
+		for _, c := range checks {
+			if c.Error == "" {
+				fmt.Printf("%s: ok\n", c.Target)
+			} else {
+				fmt.Printf("%s: %s\n", c.Target, c.Error)
+			}
+		}
+	}
+}
+
+// findingName 头的描述
+func findingName(f embed.Finding) string {
+	var name = fmt.Sprintf("block at %#x", f.HeaderOffset())
+	if f.Name != "" {
+		name += " (" + f.Name + ")"
+	}
+
+	return name
+}
+
+// verifyFile 校验头、数据、签名及载荷，有错误时退出码非0
+func verifyFile(file string) {
+	var emd = openFile(file)
+
+	findings, err := emd.Fsck()
+	if err != nil {
+		fmt.Printf("Error checking file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	var (
+		checks []check
+		failed bool
+	)
+	for _, f := range findings {
+		if errors.Is(f.Err, embed.ErrNotHeader) {
+			continue
+		}
+
+		var c = check{Target: findingName(f)}
+		if f.Err != nil {
+			c.Error, failed = f.Err.Error(), true
+		}
+
+		checks = append(checks, c)
+	}
+
+	// 签名
+	if verifier != nil {
+		_, blocks := openBlocks(file)
+		for id, block := range blocks {
+			var c = check{Target: fmt.Sprintf("block %d signature", id)}
+			if err = block.Verify(verifier); err != nil {
+				c.Error, failed = err.Error(), true
+			}
+
+			checks = append(checks, c)
+		}
+	}
+
+	// 载荷
+	emd = openFile(file)
+	payloads, err := emd.Payloads()
+	if err != nil {
+		checks, failed = append(checks, check{Target: "payloads", Error: err.Error()}), true
+	}
+
+	for _, p := range payloads {
+		var c = check{Target: "payload " + p.Name()}
+		if err = p.Verify(); err != nil {
+			c.Error, failed = err.Error(), true
+		}
+
+		checks = append(checks, c)
+	}
+
+	printChecks(checks)
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// fsckFile 列出所有magic及拒绝的原因，按选项修复
+func fsckFile(file string) {
+	var emd = openFile(file)
+
+	findings, err := emd.Fsck()
+	if err != nil {
+		fmt.Printf("Error checking file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	type result struct {
+		Offset   int64
+		DataCap  uint32
+		Name     string `json:",omitempty"`
+		Error    string `json:",omitempty"`
+		Fix      string
+		Repaired bool
+	}
+
+	var (
+		results []result
+		failed  bool
+	)
+	for _, f := range findings {
+		var r = result{Offset: f.HeaderOffset(), DataCap: f.DataCap, Name: f.Name, Fix: f.Fix.String()}
+		if f.Err != nil {
+			r.Error = f.Err.Error()
+		}
+
+		if f.Fix == embed.FixHeaderCRC && repairCRC || f.Fix == embed.FixZero && zero {
+			if err = emd.Repair(f); err != nil {
+				fmt.Printf("Error repairing %s: %s\n", findingName(f), err)
+				os.Exit(1)
+			}
+
+			r.Repaired = true
+		} else if f.Err != nil && !errors.Is(f.Err, embed.ErrNotHeader) {
+			failed = true
+		}
+
+		results = append(results, r)
+	}
+
+	switch format {
+	case formatJSON:
+		printJSON(results)
+	case formatTable:
+		var rows [][]string
+		for _, r := range results {
+			var status = "ok"
+			if r.Error != "" {
+				status = r.Error
+			}
+
+			rows = append(rows, []string{fmt.Sprintf("%#x", r.Offset), fmt.Sprint(r.DataCap), r.Name, status, r.Fix, fmt.Sprint(r.Repaired)})
+		}
+
+		printTable([]string{"OFFSET", "CAP", "NAME", "STATUS", "FIX", "REPAIRED"}, rows)
+	default:
+		for _, r := range results {
+			var status = "ok"
+			if r.Error != "" {
+				status = r.Error
+			}
+
+			fmt.Printf("%#x\t%d\t%s\t%s", r.Offset, r.DataCap, r.Name, status)
+			if r.Fix != embed.FixNone.String() {
+				fmt.Printf("\tfix: %s", r.Fix)
+			}
+
+			if r.Repaired {
+				fmt.Printf(", repaired")
+			}
+			fmt.Println()
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/zooyer/golib/embed"
+)
+
+// gen 生成Size常量定义，可由go:generate调用：
+//
+//	//go:generate go run github.com/zooyer/golib/embed/cmd gen --output size.go 3KB 100KB 48MB
+func gen(args []string) {
+	output, args := popOption(args, "output")
+	pkg, args := popOption(args, "package")
+
+	// go generate 时默认为当前包
+	if pkg == "" {
+		pkg = os.Getenv("GOPACKAGE")
+	}
+
+	if pkg == "" {
+		pkg = "main"
+	}
+
+	if len(args) == 0 {
+		help("%s: %s gen <capacity>..., the capacity is missing.", this, this)
+	}
+
+	var capacities = make([]uint32, 0, len(args))
+	for _, arg := range args {
+		capacity, err := embed.ParseCapacity(arg)
+		if err != nil {
+			help("%s: %s.", this, err)
+		}
+
+		capacities = append(capacities, capacity)
+	}
+
+	var buf bytes.Buffer
+	if err := embed.GenerateSizes(&buf, pkg, capacities...); err != nil {
+		fmt.Printf("Error generating sizes: %s\n", err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		_, _ = os.Stdout.Write(buf.Bytes())
+		return
+	}
+
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		fmt.Printf("Error writing file %s: %s\n", output, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Generate %s successful.\n", output)
+}
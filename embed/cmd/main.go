@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
@@ -15,14 +19,38 @@ import (
 type Command string
 
 const (
-	Show   Command = "show"
-	Print  Command = "print"
-	Import Command = "import"
-	Export Command = "export"
-	Help   Command = "help"
+	Show    Command = "show"
+	Print   Command = "print"
+	Import  Command = "import"
+	Export  Command = "export"
+	Chain   Command = "chain"
+	Unchain Command = "unchain"
+	Index   Command = "index"
+	Unindex Command = "unindex"
+	Add     Command = "add"
+	List    Command = "list"
+	Extract Command = "extract"
+	Strip   Command = "strip"
+	Set     Command = "set"
+	Get     Command = "get"
+	History Command = "revisions"
+	Revert  Command = "rollback"
+	Diff    Command = "diff"
+	Copy    Command = "transplant"
+	Verify  Command = "verify"
+	Fsck    Command = "fsck"
+	Apply   Command = "apply"
+	Reset   Command = "reset"
+	Patch   Command = "patch"
+	Upgrade Command = "upgrade"
+	Gen     Command = "gen"
+	Help    Command = "help"
 )
 
-var commands = []Command{Show, Print, Import, Export, Help}
+// readCommands 只读的命令，加共享锁，其余命令加排它锁
+var readCommands = []Command{Show, Print, Export, List, Extract, Get, History, Diff, Verify}
+
+var commands = []Command{Show, Print, Import, Export, Chain, Unchain, Index, Unindex, Add, List, Extract, Strip, Set, Get, History, Revert, Diff, Copy, Verify, Fsck, Apply, Reset, Patch, Upgrade, Help}
 
 var (
 	block1 = embed.MustMalloc(embed.Size1KB + "1")
@@ -31,6 +59,27 @@ var (
 
 var _, this = filepath.Split(os.Args[0])
 
+const stdio = "-" // 标准输入或标准输出
+
+var (
+	compress   = embed.CompressNone // 导入时使用的压缩算法
+	encrypt    = embed.EncryptNone  // 导入时使用的加密算法
+	key        embed.KeyProvider    // 加解密密钥
+	signer     ed25519.PrivateKey   // 导入时使用的签名私钥
+	verifier   ed25519.PublicKey    // 读取时校验签名的公钥
+	atomic     bool                 // 导入时使用A/B槽
+	repairCRC  bool                 // fsck时修复头crc32
+	zero       bool                 // fsck时清空数据损坏的块
+	revs       int                  // 导入时版本环的槽数
+	patchCount = -1                 // patch时字符串出现的次数
+	pad        bool                 // patch时替换内容不足时以NUL填充
+	dryRun     bool                 // patch时仅打印偏移
+	noBackup   bool                 // patch时不备份文件
+	readOnly   bool                 // 只读的命令
+)
+
+var extSize uint32 = embed.DefaultExtSize // upgrade时预留的扩展区大小
+
 func help(format string, v ...any) {
 	fmt.Println(fmt.Sprintf(format, v...))
 	fmt.Println("See 'embed help'")
@@ -39,23 +88,74 @@ func help(format string, v ...any) {
 
 func usage() {
 	fmt.Printf("Usage: %s source_file <COMMAND> <BLOCK> <import_file | export_file>\n", this)
+	fmt.Printf("       %s source_file <add | list | extract | strip> [payload_file | name] [name | extract_file]\n", this)
+	fmt.Printf("       %s source_file <set | get> <BLOCK> [key=value... | key]\n", this)
+	fmt.Printf("       %s source_file <diff | transplant> target_file\n", this)
+	fmt.Printf("       %s source_file reset\n", this)
+	fmt.Printf("       %s source_file upgrade [--ext-size n]\n", this)
+	fmt.Printf("       %s source_file patch <old> <new> --count n [--pad] [--dry-run] [--no-backup]\n", this)
+	fmt.Printf("       %s source_file <verify | fsck> [--repair-crc] [--zero]\n", this)
+	fmt.Printf("       %s source_file apply <manifest.json | ->\n", this)
+	fmt.Printf("       %s gen [--package name] [--output file] <capacity>...\n", this)
 	fmt.Println()
 	fmt.Println("desc...")
 	fmt.Println()
-	fmt.Println("Blocks(all|number):")
+	fmt.Println("Blocks(all|number|name):")
 	fmt.Println("  all\t\tAll blocks")
 	fmt.Println("  0\t\tBlock number 0")
 	fmt.Println("  1\t\tBlock number 1")
 	fmt.Println("  2\t\tBlock number 2")
 	fmt.Println("  ...\t\tBlock number ...")
+	fmt.Println("  name\t\tBlock tagged with name")
 	fmt.Println()
 
 	fmt.Println("Commands:")
 	fmt.Println("  show\t\tPrints blocks info")
-	fmt.Println("  import\t\tImport files into blocks")
-	fmt.Println("  export\t\tExport blocks to files")
+	fmt.Println("  import\t\tImport files into blocks, - reads a single block from stdin")
+	fmt.Println("  export\t\tExport blocks to files, - writes a single block to stdout")
+	fmt.Println("  chain\t\tChain the following blocks after the block")
+	fmt.Println("  unchain\tSplit the chained block into empty blocks")
+	fmt.Println("  index\t\tWrite a block index to the end of the file, avoiding scans")
+	fmt.Println("  unindex\tRemove the block index")
+	fmt.Println("  add\t\tAppend a file as payload, named by the file name or the name given")
+	fmt.Println("  list\t\tList appended payloads")
+	fmt.Println("  extract\tExtract the payload to a file")
+	fmt.Println("  strip\t\tRemove the payload, or all payloads without name")
+	fmt.Println("  set\t\tSet keys of the JSON object in the block, like port=8080 or db.host=\"x\"")
+	fmt.Println("  get\t\tPrint the JSON value in the block, or the value of the key")
+	fmt.Println("  revisions\tList the revisions kept by the atomic block")
+	fmt.Println("  rollback\tRoll the atomic block back to the revision, or the previous one")
+	fmt.Println("  diff\t\tCompare the blocks with the blocks of the target file")
+	fmt.Println("  transplant\tCopy the block data into the matching blocks of the target file")
+	fmt.Println("  verify\t\tCheck headers, data, signatures and payloads, exit non-zero on any error")
+	fmt.Println("  fsck\t\tList every magic occurrence with the reason it is rejected")
+	fmt.Println("  apply\t\tWrite blocks by names or numbers from files, strings or environment variables:")
+	fmt.Println("  \t\t{\"config\": {\"file\": \"config.json\"}, \"0\": {\"string\": \"...\"}, \"key\": {\"env\": \"NAME\"}}")
+	fmt.Println("  reset\t\tRestore all blocks to the build-time state and remove trailers, printing the sha256")
+	fmt.Println("  patch\t\tReplace a string outside blocks, like a build ID, backing up to source_file.bak, or to .bak.1, .bak.2 and so on if it exists")
+	fmt.Println("  upgrade\tRewrite version 1 block headers as version 2 with an extension area, in place")
+	fmt.Println("  gen\t\tGenerate Size constants for capacities like 3KB, 100KB or 48MB")
 	fmt.Println("  help\tPrints this help message")
 	fmt.Println()
+
+	fmt.Println("Options:")
+	fmt.Println("  --compress <none|deflate|gzip>\tCompress imported files")
+	fmt.Println("  --encrypt <none|aes-gcm>\tEncrypt imported files")
+	fmt.Println("  --key-env <name>\t\tRead hex encoded key from environment variable")
+	fmt.Println("  --key-file <file>\t\tRead raw or hex encoded key from file")
+	fmt.Println("  --sign-key <file>\t\tSign imported files with ed25519 private key")
+	fmt.Println("  --verify-key <file>\t\tVerify block signatures with ed25519 public key")
+	fmt.Println("  --atomic\t\t\tImport into A/B slots, crash-safe with half capacity")
+	fmt.Println("  --format <text|json|table>\tOutput format of show, list, revisions, verify and fsck")
+	fmt.Println("  --repair-crc\t\t\tRepair headers with only a bad checksum when fsck")
+	fmt.Println("  --zero\t\t\tEmpty blocks with intact header but corrupt data when fsck")
+	fmt.Println("  --revisions <n>\t\tImport into a ring keeping n revisions, 1/n capacity")
+	fmt.Println("  --count <n>\t\t\tNumber of occurrences the patch requires, nothing is patched otherwise")
+	fmt.Println("  --pad\t\t\t\tPad a shorter patch replacement with NUL bytes")
+	fmt.Println("  --dry-run\t\t\tPrint the offsets of the occurrences without patching")
+	fmt.Println("  --no-backup\t\t\tPatch without backing up the file")
+	fmt.Println("  --ext-size <n>\t\tSize of the extension area reserved by upgrade, default 64")
+	fmt.Println()
 	// embed file COMMAND BLOCK file...
 }
 
@@ -73,85 +173,228 @@ func isNumber(str string) bool {
 	return true
 }
 
-func openBlocks(file string) (*embed.Embed, []embed.Block) {
-	emd, err := embed.Open(file)
+func isName(str string) bool {
+	return str != "" && !isAll(str) && !isNumber(str)
+}
+
+// nameID 按名称查找块编号
+func nameID(file, name string) int {
+	_, blocks := openBlocks(file)
+
+	for id, block := range blocks {
+		if block.Name() == name {
+			return id
+		}
+	}
+
+	fmt.Printf("Block %s not found\n", name)
+	os.Exit(1)
+
+	return -1
+}
+
+// popOption 取出--name value或--name=value形式的选项
+func popOption(args []string, name string) (value string, rest []string) {
+	var option = "--" + name
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == option && i+1 < len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], option+"="):
+			value = strings.TrimPrefix(args[i], option+"=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	return
+}
+
+// popFlag 取出--name形式的开关选项
+func popFlag(args []string, name string) (exists bool, rest []string) {
+	for _, arg := range args {
+		if arg == "--"+name {
+			exists = true
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	return
+}
+
+// countWriter 统计写入长度
+type countWriter int64
+
+func (c *countWriter) Write(data []byte) (int, error) {
+	*c += countWriter(len(data))
+	return len(data), nil
+}
+
+// storedSize 计算文件压缩后的大小
+func storedSize(file io.ReadSeeker) (size int64, err error) {
+	var count countWriter
+
+	writer, err := compress.NewWriter(&count)
 	if err != nil {
-		fmt.Printf("Error opening file %s: %s\n", file, err)
-		os.Exit(1)
+		return
 	}
 
+	if _, err = io.Copy(writer, file); err != nil {
+		return
+	}
+
+	if err = writer.Close(); err != nil {
+		return
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	size = int64(count) + int64(encrypt.Overhead())
+	if signer != nil {
+		size += ed25519.SignatureSize
+	}
+
+	return
+}
+
+// memoryFile 读入内存的标准输入
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// openSource 打开导入的文件，-为标准输入，读入内存以便计算大小后重新读取
+func openSource(filename string) (io.ReadSeekCloser, error) {
+	if filename != stdio {
+		return os.Open(filename)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	return memoryFile{Reader: bytes.NewReader(data)}, nil
+}
+
+// setupBlock 设置写入时使用的压缩、加密、签名及A/B槽选项
+func setupBlock(block *embed.Block) (err error) {
+	if err = block.SetCompression(compress); err != nil {
+		return
+	}
+
+	if err = block.SetEncryption(encrypt); err != nil {
+		return
+	}
+
+	block.SetSigner(signer)
+
+	if atomic {
+		block.SetAtomic(true)
+	}
+
+	if revs > 0 {
+		return block.SetRevisions(revs)
+	}
+
+	return
+}
+
+// importFile 流式导入文件到块
+func importFile(block *embed.Block, filename string) (err error) {
+	file, err := openSource(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if err = setupBlock(block); err != nil {
+		return
+	}
+
+	size, err := storedSize(file)
+	if err != nil {
+		return
+	}
+
+	// 提前校验，避免写入一半失败
+	if err = block.CheckWrite(uint32(min(size, math.MaxUint32))); err != nil {
+		return fmt.Errorf("file %s: %w", filename, err)
+	}
+
+	var writer = block.NewWriter()
+	if _, err = io.Copy(writer, file); err != nil {
+		return
+	}
+
+	return writer.Close()
+}
+
+func openBlocks(file string) (*embed.Embed, []embed.Block) {
+	var emd = openFile(file)
+
 	blocks, err := emd.Blocks()
 	if err != nil {
 		fmt.Printf("Error getting blocks: %s\n", err)
 		os.Exit(1)
 	}
 
+	for i := range blocks {
+		blocks[i].SetKey(key)
+		blocks[i].SetVerifier(verifier)
+	}
+
 	return emd, blocks
 }
 
 func showID(file string, id int) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	if id >= len(blocks) {
 		fmt.Printf("Block %d not found\n", id)
 		os.Exit(1)
 	}
 
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing file %s: %s\n", file, err)
-		os.Exit(1)
-	}
-
-	var block = blocks[id]
-	fmt.Printf("Block %d:\n", id)
-	fmt.Println(block.String())
-	fmt.Println()
+	printBlocks(blocks, []int{id})
 }
 
 func showAll(file string) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing file %s: %s\n", file, err)
-		os.Exit(1)
+	var ids = make([]int, len(blocks))
+	for id := range blocks {
+		ids[id] = id
 	}
 
-	for id, block := range blocks {
-		fmt.Printf("Block %d:\n", id)
-		fmt.Println(block.String())
-		fmt.Println()
-	}
+	printBlocks(blocks, ids)
 }
 
 func importID(file string, id int, filename string) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	if id >= len(blocks) {
 		fmt.Printf("Block %d not found\n", id)
 		os.Exit(1)
 	}
 
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		fmt.Printf("Error reading file %s: %s\n", filename, err)
-		os.Exit(1)
-	}
-
-	if _, err = blocks[id].Write(data); err != nil {
+	if err := importFile(&blocks[id], filename); err != nil {
 		fmt.Printf("Error writing block %d: %s\n", id, err)
 		os.Exit(1)
 	}
 
-	if err = emd.Close(); err != nil {
-		fmt.Printf("Error closing block %d: %s\n", id, err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Import block %d successful.\n", id)
 }
 
 func importAll(file string, filenames ...string) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	if len(blocks) != len(filenames) {
 		fmt.Printf("Block count mismatch: %d != %d\n", len(blocks), len(filenames))
@@ -159,84 +402,131 @@ func importAll(file string, filenames ...string) {
 	}
 
 	for id := range blocks {
-		data, err := os.ReadFile(filenames[id])
-		if err != nil {
-			fmt.Printf("Error reading file %s: %s\n", filenames[id], err)
-			os.Exit(1)
-		}
-
-		if _, err = blocks[id].Write(data); err != nil {
+		if err := importFile(&blocks[id], filenames[id]); err != nil {
 			fmt.Printf("Error writing block %d: %s\n", id, err)
 			os.Exit(1)
 		}
 	}
 
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing blocks: %s\n", err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Import blocks successful.\n")
 }
 
 func exportID(file string, id int, filename string) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	if id >= len(blocks) {
-		fmt.Printf("Block %d not found\n", id)
+		fmt.Fprintf(os.Stderr, "Block %d not found\n", id)
 		os.Exit(1)
 	}
 
-	var (
-		block = blocks[id]
-		buf   = make([]byte, block.Len())
-	)
-
-	if _, err := block.Read(buf); err != nil {
-		fmt.Printf("Error reading block %d: %s\n", id, err)
-		os.Exit(1)
+	var err error
+	if filename == stdio {
+		_, err = io.Copy(os.Stdout, blocks[id].NewReader())
+	} else {
+		err = embed.Export(filename, blocks[id])
 	}
 
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing block %d: %s\n", id, err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing block %d: %s\n", id, err)
 		os.Exit(1)
 	}
 
-	if err := os.WriteFile(filename, buf, 0644); err != nil {
-		fmt.Printf("Error writing block %d: %s\n", id, err)
-		os.Exit(1)
+	// 输出到标准输出时不打印提示
+	if filename != stdio {
+		fmt.Printf("Export block %d successful.\n", id)
 	}
-
-	fmt.Printf("Export block %d successful.\n", id)
 }
 
 func exportAll(file, filename string) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	var format = fmt.Sprintf("%%0%dd", len(strconv.Itoa(len(blocks))))
 	for i, block := range blocks {
-		var buf = make([]byte, block.Len())
-		if _, err := block.Read(buf); err != nil {
-			fmt.Printf("Error reading block %d: %s\n", i, err)
+		if err := embed.Export(fmt.Sprintf(filename+"."+format, i), block); err != nil {
+			fmt.Printf("Error writing block %d: %s\n", i, err)
 			os.Exit(1)
 		}
+	}
 
-		if err := os.WriteFile(fmt.Sprintf(filename+"."+format, i), buf, 0644); err != nil {
-			fmt.Printf("Error writing block %d: %s\n", i, err)
+	fmt.Printf("Export blocks successful.\n")
+}
+
+// parseID 解析块编号或名称
+func parseID(file, block string) int {
+	if isName(block) {
+		return nameID(file, block)
+	}
+
+	id, err := strconv.Atoi(block)
+	if err != nil {
+		help("%s: '%s' is not a block id.", this, block)
+	}
+
+	return id
+}
+
+func chainIDs(file string, ids ...int) {
+	emd, blocks := openBlocks(file)
+
+	var chain = make([]*embed.Block, 0, len(ids))
+	for _, id := range ids {
+		if id >= len(blocks) {
+			fmt.Printf("Block %d not found\n", id)
 			os.Exit(1)
 		}
+
+		chain = append(chain, &blocks[id])
 	}
 
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing blocks: %s\n", err)
+	block, err := emd.Chain(chain...)
+	if err != nil {
+		fmt.Printf("Error chaining blocks: %s\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Export blocks successful.\n")
+	fmt.Printf("Chain blocks successful, capacity %d.\n", block.Cap())
+}
+
+func unchainID(file string, id int) {
+	_, blocks := openBlocks(file)
+
+	if id >= len(blocks) {
+		fmt.Printf("Block %d not found\n", id)
+		os.Exit(1)
+	}
+
+	if err := blocks[id].Unchain(); err != nil {
+		fmt.Printf("Error unchaining block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Unchain block %d successful.\n", id)
+}
+
+// indexFile 写入或删除块索引
+func indexFile(file string, write bool) {
+	var (
+		emd    = openFile(file)
+		action = "Index"
+		err    error
+	)
+
+	if write {
+		err = emd.WriteIndex()
+	} else {
+		action, err = "Unindex", emd.RemoveIndex()
+	}
+
+	if err != nil {
+		fmt.Printf("Error indexing file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s file %s successful.\n", action, file)
 }
 
 func printID(file string, id int) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	if id >= len(blocks) {
 		fmt.Printf("Block %d not found\n", id)
@@ -253,33 +543,23 @@ func printID(file string, id int) {
 		os.Exit(1)
 	}
 
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing block %d: %s\n", id, err)
-		os.Exit(1)
-	}
-
 	fmt.Printf("Block %d:\n", id)
 	fmt.Printf("%s\n", buf)
 }
 
 func printAll(file string) {
-	emd, blocks := openBlocks(file)
+	_, blocks := openBlocks(file)
 
 	for id, block := range blocks {
 		var buf = make([]byte, block.Len())
 		if _, err := block.Read(buf); err != nil {
-			fmt.Printf("Error reading block %d: %s\n", block, err)
+			fmt.Printf("Error reading block %d: %s\n", id, err)
 			os.Exit(1)
 		}
 
 		fmt.Printf("Block %d:\n", id)
 		fmt.Printf("%s\n", buf)
 	}
-
-	if err := emd.Close(); err != nil {
-		fmt.Printf("Error closing blocks: %s\n", err)
-		os.Exit(1)
-	}
 }
 
 func main() {
@@ -288,10 +568,100 @@ func main() {
 		os.Exit(1)
 	}
 
-	var (
-		args = os.Args
-		file = args[1]
-	)
+	// 解析选项
+	var args = os.Args
+	if name, rest := popOption(args, "compress"); name != "" {
+		var err error
+		if compress, err = embed.ParseCompression(name); err != nil {
+			help("%s: %s.", this, err)
+		}
+		args = rest
+	}
+
+	if name, rest := popOption(args, "encrypt"); name != "" {
+		var err error
+		if encrypt, err = embed.ParseEncryption(name); err != nil {
+			help("%s: %s.", this, err)
+		}
+		args = rest
+	}
+
+	if name, rest := popOption(args, "key-env"); name != "" {
+		key = embed.KeyEnv(name)
+		args = rest
+	}
+
+	if filename, rest := popOption(args, "key-file"); filename != "" {
+		key = embed.KeyFile(filename)
+		args = rest
+	}
+
+	atomic, args = popFlag(args, "atomic")
+	pad, args = popFlag(args, "pad")
+	dryRun, args = popFlag(args, "dry-run")
+	noBackup, args = popFlag(args, "no-backup")
+	repairCRC, args = popFlag(args, "repair-crc")
+	zero, args = popFlag(args, "zero")
+
+	if name, rest := popOption(args, "format"); name != "" {
+		parseFormat(name)
+		args = rest
+	}
+
+	if count, rest := popOption(args, "revisions"); count != "" {
+		var err error
+		if revs, err = strconv.Atoi(count); err != nil {
+			help("%s: '%s' is not a revision count.", this, count)
+		}
+		args = rest
+	}
+
+	if count, rest := popOption(args, "count"); count != "" {
+		var err error
+		if patchCount, err = strconv.Atoi(count); err != nil || patchCount < 0 {
+			help("%s: '%s' is not an occurrence count.", this, count)
+		}
+		args = rest
+	}
+
+	if size, rest := popOption(args, "ext-size"); size != "" {
+		n, err := strconv.ParseUint(size, 10, 32)
+		if err != nil {
+			help("%s: '%s' is not an extension size.", this, size)
+		}
+		extSize, args = uint32(n), rest
+	}
+
+	if filename, rest := popOption(args, "sign-key"); filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			help("%s: %s.", this, err)
+		}
+
+		if signer, err = embed.ParsePrivateKey(data); err != nil {
+			help("%s: %s.", this, err)
+		}
+		args = rest
+	}
+
+	if filename, rest := popOption(args, "verify-key"); filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			help("%s: %s.", this, err)
+		}
+
+		if verifier, err = embed.ParsePublicKey(data); err != nil {
+			help("%s: %s.", this, err)
+		}
+		args = rest
+	}
+
+	if len(args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	var file = args[1]
 
 	// 帮助
 	if Command(file) == Help {
@@ -299,6 +669,12 @@ func main() {
 		os.Exit(0)
 	}
 
+	// 生成Size常量
+	if Command(file) == Gen {
+		gen(args[2:])
+		return
+	}
+
 	// 解析命令
 	var command = Show
 	if args = args[2:]; len(args) > 0 {
@@ -311,6 +687,104 @@ func main() {
 		help("%s: '%s' is not a embed command.", this, command)
 	}
 
+	readOnly = slices.Contains(readCommands, command)
+	defer closeFiles()
+
+	// 索引
+	if command == Index || command == Unindex {
+		indexFile(file, command == Index)
+		return
+	}
+
+	// 按清单写入
+	if command == Apply {
+		if len(args) == 0 {
+			help("%s: %s %s apply <manifest.json>, the manifest file is missing.", this, this, file)
+		}
+
+		applyManifest(file, args[0])
+		return
+	}
+
+	// 升级头格式
+	if command == Upgrade {
+		upgradeFile(file)
+		return
+	}
+
+	// 恢复编译时的状态
+	if command == Reset {
+		resetFile(file)
+		return
+	}
+
+	// 替换字符串
+	if command == Patch {
+		if len(args) < 2 {
+			help("%s: %s %s patch <old> <new> --count n, the old or new string is missing.", this, this, file)
+		}
+
+		if patchCount < 0 {
+			help("%s: %s %s patch <old> <new> --count n, the occurrence count is missing.", this, this, file)
+		}
+
+		patchFile(file, args[0], args[1])
+		return
+	}
+
+	// 校验文件
+	if command == Verify || command == Fsck {
+		if command == Verify {
+			verifyFile(file)
+		} else {
+			fsckFile(file)
+		}
+		return
+	}
+
+	// 比较或迁移两个文件的块
+	if command == Diff || command == Copy {
+		if len(args) == 0 {
+			help("%s: %s %s %s <target_file>, the target file is missing.", this, this, file, command)
+		}
+
+		if command == Diff {
+			diffFiles(file, args[0])
+		} else {
+			transplantFiles(file, args[0])
+		}
+		return
+	}
+
+	// 追加载荷
+	var name string
+	if command == Strip && len(args) > 0 {
+		name = args[0]
+	} else if command == Add && len(args) > 1 {
+		name = args[1]
+	}
+
+	switch command {
+	case Add:
+		if len(args) == 0 {
+			help("%s: %s %s add <payload_file> [name], the payload file is missing.", this, this, file)
+		}
+		addPayload(file, args[0], name)
+		return
+	case List:
+		listPayloads(file)
+		return
+	case Extract:
+		if len(args) < 2 {
+			help("%s: %s %s extract <name> <extract_file>, the name or extract file is missing.", this, this, file)
+		}
+		extractPayload(file, args[0], args[1])
+		return
+	case Strip:
+		stripPayloads(file, name)
+		return
+	}
+
 	// block
 	var block string
 	if len(args) > 0 {
@@ -318,28 +792,26 @@ func main() {
 		args = args[1:]
 	}
 
-	if command == Show || command == Print && block == "" {
+	if (command == Show || command == Print) && block == "" {
 		block = "all"
 	}
 
-	// 校验block
-	if !isAll(block) && !isNumber(block) {
-		help("%s: '%s' is not a block id.", this, block)
-	}
-
 	// 获取block id
 	var id int
 	if !isAll(block) {
-		var err error
-		if id, err = strconv.Atoi(block); err != nil {
-			help("%s: '%s' is not a block id.", this, block)
-		}
+		id = parseID(file, block)
+	} else if command == Chain || command == Unchain || command == Set || command == Get || command == History || command == Revert {
+		help("%s: '%s' requires a block id.", this, command)
 	}
 
 	var files = args
 
+	if command == Set && len(files) == 0 {
+		help("%s: %s %s set <BLOCK> <key=value>..., the key=value pairs are missing.", this, this, file)
+	}
+
 	// 校验目标文件
-	if command != Show && command != Print && command != Help && len(files) == 0 {
+	if command != Show && command != Print && command != Unchain && command != Get && command != History && command != Revert && command != Help && len(files) == 0 {
 		var params = strings.Join(os.Args[1:], " ")
 		help("%s: %s %s <%s_file>, the %s file is missing.", this, this, params, command, command)
 	}
@@ -359,17 +831,49 @@ func main() {
 			printID(file, id)
 		}
 	case Import:
+		if isAll(block) && slices.Contains(files, stdio) {
+			help("%s: '%s' is only supported for a single block.", this, stdio)
+		}
+
 		if isAll(block) {
 			importAll(file, files...)
 		} else {
 			importID(file, id, files[0])
 		}
 	case Export:
+		if isAll(block) && files[0] == stdio {
+			help("%s: '%s' is only supported for a single block.", this, stdio)
+		}
+
 		if isAll(block) {
 			exportAll(file, files[0])
 		} else {
 			exportID(file, id, files[0])
 		}
+	case Chain:
+		var ids = []int{id}
+		for _, block := range files {
+			ids = append(ids, parseID(file, block))
+		}
+		chainIDs(file, ids...)
+	case Unchain:
+		unchainID(file, id)
+	case Set:
+		setValues(file, id, files...)
+	case Get:
+		var key string
+		if len(files) > 0 {
+			key = files[0]
+		}
+		getValue(file, id, key)
+	case History:
+		listRevisions(file, id)
+	case Revert:
+		var number string
+		if len(files) > 0 {
+			number = files[0]
+		}
+		rollbackID(file, id, number)
 	case Help:
 		usage()
 		os.Exit(0)
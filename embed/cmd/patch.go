@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// backupFile 复制文件为file.bak，已存在时依次使用file.bak.1、file.bak.2等，
+// 不覆盖之前的备份
+func backupFile(file string) (backup string, err error) {
+	source, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return
+	}
+
+	var target *os.File
+	for i := 0; target == nil; i++ {
+		if backup = file + ".bak"; i > 0 {
+			backup += "." + strconv.Itoa(i)
+		}
+
+		target, err = os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return
+		}
+	}
+
+	if _, err = io.Copy(target, source); err != nil {
+		_ = target.Close()
+		return
+	}
+
+	return backup, target.Close()
+}
+
+// patchFile 替换文件中的字符串，次数必须与count一致
+func patchFile(file, old, new string) {
+	var emd = openFile(file)
+
+	// 校验通过后再备份，仅打印偏移时不修改
+	offsets, err := emd.CheckPatch([]byte(old), []byte(new), patchCount, pad)
+	for _, offset := range offsets {
+		fmt.Printf("%#x\n", offset)
+	}
+
+	if err != nil {
+		fmt.Printf("Error patching file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	if dryRun {
+		fmt.Printf("Found %d occurrences, nothing patched.\n", len(offsets))
+		return
+	}
+
+	if !noBackup {
+		backup, err := backupFile(file)
+		if err != nil {
+			fmt.Printf("Error backing up file %s: %s\n", file, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Backup file %s to %s.\n", file, backup)
+	}
+
+	if _, err = emd.Patch([]byte(old), []byte(new), patchCount, pad); err != nil {
+		fmt.Printf("Error patching file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Patch %d occurrences successful.\n", len(offsets))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zooyer/golib/embed"
+)
+
+func openFile(file string) *embed.Embed {
+	return openLocked(file, !readOnly)
+}
+
+// opened 命令打开的文件，同一文件在整个命令中只打开一次并持有同一把锁，避免
+// 按名称查找块与修改之间被其他embed进程修改，命令结束时由closeFiles关闭
+var opened = make(map[string]*embed.Embed)
+
+// openLocked 打开文件并加锁，避免与其他embed进程交错修改
+func openLocked(file string, exclusive bool) *embed.Embed {
+	if emd, exists := opened[file]; exists {
+		return emd
+	}
+
+	emd, err := embed.Open(file)
+	if err != nil {
+		fmt.Printf("Error opening file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	if err = emd.Lock(exclusive); err != nil {
+		fmt.Printf("Error locking file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+	opened[file] = emd
+
+	return emd
+}
+
+// closeFiles 关闭命令打开的文件并释放锁
+func closeFiles() {
+	for file, emd := range opened {
+		if err := emd.Close(); err != nil {
+			fmt.Printf("Error closing file %s: %s\n", file, err)
+			os.Exit(1)
+		}
+
+		delete(opened, file)
+	}
+}
+
+// addPayload 追加文件，名称默认为文件名
+func addPayload(file, filename, name string) {
+	if name == "" {
+		_, name = filepath.Split(filename)
+	}
+
+	source, err := os.Open(filename)
+	if err != nil {
+		fmt.Printf("Error opening file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		fmt.Printf("Error opening file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	var emd = openFile(file)
+	if err = emd.AddPayload(name, source, info.ModTime()); err != nil {
+		fmt.Printf("Error adding payload %s: %s\n", name, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Add payload %s successful.\n", name)
+}
+
+func listPayloads(file string) {
+	var emd = openFile(file)
+
+	payloads, err := emd.Payloads()
+	if err != nil {
+		fmt.Printf("Error getting payloads: %s\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case formatJSON:
+		var objects = make([]map[string]any, 0, len(payloads))
+		for _, p := range payloads {
+			objects = append(objects, map[string]any{"Name": p.Name(), "Size": p.Size(), "ModTime": p.ModTime()})
+		}
+
+		printJSON(objects)
+	case formatTable:
+		var rows [][]string
+		for _, p := range payloads {
+			rows = append(rows, []string{p.Name(), fmt.Sprint(p.Size()), formatTime(p.ModTime())})
+		}
+
+		printTable([]string{"NAME", "SIZE", "MODIFIED"}, rows)
+	default:
+		for _, p := range payloads {
+			fmt.Println(p.String())
+		}
+	}
+}
+
+func extractPayload(file, name, filename string) {
+	var emd = openFile(file)
+
+	payload, err := emd.Payload(name)
+	if err != nil {
+		fmt.Printf("Error getting payload: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err = payload.Verify(); err != nil {
+		fmt.Printf("Error verifying payload: %s\n", err)
+		os.Exit(1)
+	}
+
+	output, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		fmt.Printf("Error opening file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	if _, err = io.Copy(output, payload.NewReader()); err != nil {
+		fmt.Printf("Error writing file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	if err = output.Close(); err != nil {
+		fmt.Printf("Error closing file %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Extract payload %s successful.\n", name)
+}
+
+// stripPayloads 删除指定载荷，未指定时删除全部载荷
+func stripPayloads(file, name string) {
+	var (
+		emd = openFile(file)
+		err error
+	)
+
+	if name == "" {
+		err = emd.StripPayloads()
+	} else {
+		err = emd.RemovePayload(name)
+	}
+
+	if err != nil {
+		fmt.Printf("Error stripping payloads: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Strip payloads successful.\n")
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// resetFile 恢复所有块为编译时的状态，并打印文件的sha256
+func resetFile(file string) {
+	var emd = openFile(file)
+
+	count, err := emd.Reset()
+	if err != nil {
+		fmt.Printf("Error resetting file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	source, err := os.Open(file)
+	if err != nil {
+		fmt.Printf("Error opening file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+	defer source.Close()
+
+	var hash = sha256.New()
+	if _, err = io.Copy(hash, source); err != nil {
+		fmt.Printf("Error reading file %s: %s\n", file, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Reset %d blocks successful.\n", count)
+	fmt.Printf("sha256 %x\n", hash.Sum(nil))
+}
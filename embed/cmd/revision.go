@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func listRevisions(file string, id int) {
+	_, blocks := openBlocks(file)
+
+	if id >= len(blocks) {
+		fmt.Printf("Block %d not found\n", id)
+		os.Exit(1)
+	}
+
+	revisions, err := blocks[id].Revisions()
+	if err != nil {
+		fmt.Printf("Error getting revisions of block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case formatJSON:
+		printJSON(revisions)
+	case formatTable:
+		var rows [][]string
+		for _, r := range revisions {
+			rows = append(rows, []string{fmt.Sprint(r.Number), fmt.Sprint(r.Len), formatTime(r.Time), fmt.Sprint(r.Active)})
+		}
+
+		printTable([]string{"REVISION", "LEN", "TIME", "ACTIVE"}, rows)
+	default:
+		for _, r := range revisions {
+			fmt.Println(r.String())
+		}
+	}
+}
+
+// rollbackID 回滚到指定版本，未指定时回滚到上一个版本
+func rollbackID(file string, id int, number string) {
+	_, blocks := openBlocks(file)
+
+	if id >= len(blocks) {
+		fmt.Printf("Block %d not found\n", id)
+		os.Exit(1)
+	}
+
+	var block = &blocks[id]
+	revisions, err := block.Revisions()
+	if err != nil {
+		fmt.Printf("Error getting revisions of block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	var rev uint64
+	if number != "" {
+		if rev, err = strconv.ParseUint(number, 10, 32); err != nil {
+			help("%s: '%s' is not a revision.", this, number)
+		}
+	} else {
+		// 生效的版本总是最新的，回滚到次新的版本
+		if len(revisions) < 2 {
+			fmt.Printf("Block %d has no previous revision\n", id)
+			os.Exit(1)
+		}
+
+		rev = uint64(revisions[1].Number)
+	}
+
+	if err = block.Rollback(uint32(rev)); err != nil {
+		fmt.Printf("Error rolling back block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Rollback block %d to revision %d successful.\n", id, rev)
+}
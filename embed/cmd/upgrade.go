@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+)
+
+// upgradeFile 将v1的块升级为v2，容量不足的块、原子块及块链跳过
+func upgradeFile(file string) {
+	_, blocks := openBlocks(file)
+
+	var upgraded int
+	for id := range blocks {
+		var block = &blocks[id]
+		if version := block.Version(); version >= 2 {
+			fmt.Printf("Block %d: version %d, skipped\n", id, version)
+			continue
+		}
+
+		if err := block.Upgrade(extSize); err != nil {
+			fmt.Printf("Block %d: %s, skipped\n", id, err)
+			continue
+		}
+
+		upgraded++
+		fmt.Printf("Block %d: upgraded to version %d, extension area %d bytes\n", id, block.Version(), extSize)
+	}
+
+	fmt.Printf("Upgrade %d blocks successful.\n", upgraded)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zooyer/golib/embed"
+)
+
+// readObject 读取块中的JSON对象，空块视为空对象
+func readObject(block *embed.Block) (object map[string]any, err error) {
+	if block.Len() == 0 {
+		return map[string]any{}, nil
+	}
+
+	data, err := io.ReadAll(block.NewReader())
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("block is not a JSON object: %w", err)
+	}
+
+	if object == nil {
+		object = map[string]any{}
+	}
+
+	return
+}
+
+// parseValue 解析JSON值，非法JSON作为字符串
+func parseValue(str string) (value any) {
+	if err := json.Unmarshal([]byte(str), &value); err != nil {
+		return str
+	}
+
+	return
+}
+
+// setKey 按.分隔的路径设置值，自动创建中间对象
+func setKey(object map[string]any, key string, value any) (err error) {
+	var keys = strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		switch child := object[k].(type) {
+		case map[string]any:
+			object = child
+		case nil:
+			var m = map[string]any{}
+			object[k], object = m, m
+		default:
+			return fmt.Errorf("key %s is not an object", k)
+		}
+	}
+
+	object[keys[len(keys)-1]] = value
+
+	return
+}
+
+// getKey 按.分隔的路径获取值
+func getKey(object map[string]any, key string) (value any, ok bool) {
+	value = object
+	for _, k := range strings.Split(key, ".") {
+		var m map[string]any
+		if m, ok = value.(map[string]any); !ok {
+			return
+		}
+
+		if value, ok = m[k]; !ok {
+			return
+		}
+	}
+
+	return
+}
+
+func setValues(file string, id int, pairs ...string) {
+	_, blocks := openBlocks(file)
+
+	if id >= len(blocks) {
+		fmt.Printf("Block %d not found\n", id)
+		os.Exit(1)
+	}
+
+	var block = &blocks[id]
+	object, err := readObject(block)
+	if err != nil {
+		fmt.Printf("Error reading block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			help("%s: '%s' is not a key=value pair.", this, pair)
+		}
+
+		if err = setKey(object, key, parseValue(value)); err != nil {
+			fmt.Printf("Error setting %s: %s\n", key, err)
+			os.Exit(1)
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		fmt.Printf("Error encoding block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	if err = setupBlock(block); err != nil {
+		fmt.Printf("Error writing block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	if _, err = block.Write(data); err != nil {
+		fmt.Printf("Error writing block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Set block %d successful.\n", id)
+}
+
+func getValue(file string, id int, key string) {
+	_, blocks := openBlocks(file)
+
+	if id >= len(blocks) {
+		fmt.Printf("Block %d not found\n", id)
+		os.Exit(1)
+	}
+
+	object, err := readObject(&blocks[id])
+	if err != nil {
+		fmt.Printf("Error reading block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	var value any = object
+	if key != "" {
+		var ok bool
+		if value, ok = getKey(object, key); !ok {
+			fmt.Printf("Key %s not set\n", key)
+			os.Exit(1)
+		}
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding block %d: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(data))
+}
@@ -0,0 +1,111 @@
+package embed
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Compression is the compression algorithm of block data.
+type Compression uint8
+
+const (
+	CompressNone    Compression = iota // 不压缩
+	CompressDeflate                    // DEFLATE
+	CompressGzip                       // gzip
+)
+
+var compressions = map[Compression]string{
+	CompressNone:    "none",
+	CompressDeflate: "deflate",
+	CompressGzip:    "gzip",
+}
+
+func (c Compression) String() string {
+	if name, exists := compressions[c]; exists {
+		return name
+	}
+
+	return fmt.Sprintf("compression(%d)", c)
+}
+
+// ParseCompression parses the compression algorithm name.
+func ParseCompression(name string) (Compression, error) {
+	for c, n := range compressions {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return CompressNone, fmt.Errorf("unknown compression: %s", name)
+}
+
+func (c Compression) check() error {
+	if _, exists := compressions[c]; !exists {
+		return fmt.Errorf("unknown compression: %d", c)
+	}
+
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewWriter returns a writer compressing data into w.
+func (c Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressNone:
+		return nopWriteCloser{Writer: w}, nil
+	case CompressDeflate:
+		return flate.NewWriter(w, flate.BestCompression)
+	case CompressGzip:
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	}
+
+	return nil, c.check()
+}
+
+// NewReader returns a reader decompressing data from r.
+func (c Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case CompressNone:
+		return io.NopCloser(r), nil
+	case CompressDeflate:
+		return flate.NewReader(r), nil
+	case CompressGzip:
+		return gzip.NewReader(r)
+	}
+
+	return nil, c.check()
+}
+
+// encode 压缩数据
+func (c Compression) encode(data []byte) (_ []byte, err error) {
+	if c == CompressNone {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+
+	writer, err := c.NewWriter(&buf)
+	if err != nil {
+		return
+	}
+
+	if _, err = writer.Write(data); err != nil {
+		return
+	}
+
+	if err = writer.Close(); err != nil {
+		return
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,41 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	var data = bytes.Repeat([]byte("Hello World\n"), 100)
+
+	for _, c := range []Compression{CompressNone, CompressDeflate, CompressGzip} {
+		parsed, err := ParseCompression(c.String())
+		if err != nil || parsed != c {
+			t.Fatal("ParseCompression error:", c, err)
+		}
+
+		encoded, err := c.encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c != CompressNone && len(encoded) >= len(data) {
+			t.Fatal("encode not compressed:", c, len(encoded))
+		}
+
+		reader, err := c.NewReader(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := io.ReadAll(reader)
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Fatal("decode error:", c, err)
+		}
+	}
+
+	if _, err := ParseCompression("lz4"); err == nil {
+		t.Fatal("ParseCompression unknown should fail")
+	}
+}
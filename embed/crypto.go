@@ -0,0 +1,222 @@
+package embed
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Encryption is the encryption algorithm of block data.
+type Encryption uint8
+
+const (
+	EncryptNone   Encryption = iota // 不加密
+	EncryptAESGCM                   // AES-GCM，数据格式：nonce + 密文 + tag，v2头有扩展区时nonce存放在扩展区，头中的原始大小及数据标志位作为附加数据认证
+)
+
+var encryptions = map[Encryption]string{
+	EncryptNone:   "none",
+	EncryptAESGCM: "aes-gcm",
+}
+
+var (
+	ErrNoKey   = errors.New("embed: block is encrypted but no key provided")
+	ErrDecrypt = errors.New("embed: decrypt block failed, wrong key or corrupted data")
+)
+
+func (e Encryption) String() string {
+	if name, exists := encryptions[e]; exists {
+		return name
+	}
+
+	return fmt.Sprintf("encryption(%d)", e)
+}
+
+// ParseEncryption parses the encryption algorithm name.
+func ParseEncryption(name string) (Encryption, error) {
+	for e, n := range encryptions {
+		if n == name {
+			return e, nil
+		}
+	}
+
+	return EncryptNone, fmt.Errorf("unknown encryption: %s", name)
+}
+
+// Overhead returns the length added to the data by encryption at most.
+// Blocks with a header extension area store the nonce in the header, the
+// data then grows by Overhead minus the nonce size.
+func (e Encryption) Overhead() int {
+	switch e {
+	case EncryptAESGCM:
+		return e.nonceSize() + 16 // nonce + tag
+	}
+
+	return 0
+}
+
+// nonceSize nonce的长度
+func (e Encryption) nonceSize() int {
+	if e == EncryptAESGCM {
+		return 12
+	}
+
+	return 0
+}
+
+func (e Encryption) check() error {
+	if _, exists := encryptions[e]; !exists {
+		return fmt.Errorf("unknown encryption: %d", e)
+	}
+
+	return nil
+}
+
+// KeyProvider provides the key of encrypted blocks, AES-GCM accepts
+// 16, 24 or 32 bytes keys.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyFunc adapts a function to KeyProvider.
+type KeyFunc func() ([]byte, error)
+
+func (f KeyFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// parseKey 解析十六进制或原始密钥
+func parseKey(data []byte) (key []byte, err error) {
+	var text = bytes.TrimSpace(data)
+
+	if key, err = hex.DecodeString(string(text)); err == nil && checkKey(key) == nil {
+		return
+	}
+
+	if err = checkKey(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func checkKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	}
+
+	return fmt.Errorf("invalid key length: %d", len(key))
+}
+
+// KeyEnv returns a provider reading a hex encoded key from the environment variable.
+func KeyEnv(name string) KeyProvider {
+	return KeyFunc(func() ([]byte, error) {
+		value, exists := os.LookupEnv(name)
+		if !exists {
+			return nil, fmt.Errorf("environment variable %s not set", name)
+		}
+
+		return parseKey([]byte(value))
+	})
+}
+
+// KeyFile returns a provider reading a raw or hex encoded key from the file.
+func KeyFile(filename string) KeyProvider {
+	return KeyFunc(func() (_ []byte, err error) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return
+		}
+
+		return parseKey(data)
+	})
+}
+
+// KeyPassphrase returns a provider deriving a 32 bytes key from the
+// passphrase and salt with PBKDF2-SHA256.
+func KeyPassphrase(passphrase, salt string) KeyProvider {
+	return KeyFunc(func() ([]byte, error) {
+		return pbkdf2.Key(sha256.New, passphrase, []byte(salt), 600000, 32)
+	})
+}
+
+func newAEAD(provider KeyProvider) (_ cipher.AEAD, err error) {
+	if provider == nil {
+		return nil, ErrNoKey
+	}
+
+	key, err := provider.Key()
+	if err != nil {
+		return
+	}
+
+	if err = checkKey(key); err != nil {
+		return
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encrypt 加密数据，nonce存放在密文之前，由splitNonce移入头的扩展区，以附加
+// 数据aad认证头中的字段
+func (e Encryption) encrypt(provider KeyProvider, data, aad []byte) (_ []byte, err error) {
+	if e == EncryptNone {
+		return data, nil
+	}
+
+	if err = e.check(); err != nil {
+		return
+	}
+
+	aead, err := newAEAD(provider)
+	if err != nil {
+		return
+	}
+
+	var nonce = make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+
+	return aead.Seal(nonce, nonce, data, aad), nil
+}
+
+// decrypt 解密数据，data以nonce开头，aad需与加密时一致
+func (e Encryption) decrypt(provider KeyProvider, data, aad []byte) (_ []byte, err error) {
+	if e == EncryptNone {
+		return data, nil
+	}
+
+	if err = e.check(); err != nil {
+		return
+	}
+
+	aead, err := newAEAD(provider)
+	if err != nil {
+		return
+	}
+
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+
+	var nonce = data[:aead.NonceSize()]
+	if data, err = aead.Open(nil, nonce, data[aead.NonceSize():], aad); err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return data, nil
+}
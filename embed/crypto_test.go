@@ -0,0 +1,74 @@
+package embed
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryption(t *testing.T) {
+	var (
+		data  = []byte("api-secret-token")
+		key   = KeyFunc(func() ([]byte, error) { return bytes.Repeat([]byte{1}, 32), nil })
+		wrong = KeyFunc(func() ([]byte, error) { return bytes.Repeat([]byte{2}, 32), nil })
+	)
+
+	var aad = dataHeader(uint32(len(data)), uint32(EncryptAESGCM)<<4)
+
+	encrypted, err := EncryptAESGCM.encrypt(key, data, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(encrypted) != len(data)+EncryptAESGCM.Overhead() || bytes.Contains(encrypted, data) {
+		t.Fatal("encrypt error:", encrypted)
+	}
+
+	decrypted, err := EncryptAESGCM.decrypt(key, encrypted, aad)
+	if err != nil || !bytes.Equal(decrypted, data) {
+		t.Fatal("decrypt error:", err)
+	}
+
+	if _, err = EncryptAESGCM.decrypt(wrong, encrypted, aad); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("decrypt with wrong key error:", err)
+	}
+
+	if _, err = EncryptAESGCM.decrypt(nil, encrypted, aad); !errors.Is(err, ErrNoKey) {
+		t.Fatal("decrypt without key error:", err)
+	}
+
+	// 篡改认证的头字段
+	if _, err = EncryptAESGCM.decrypt(key, encrypted, dataHeader(uint32(len(data))-1, uint32(EncryptAESGCM)<<4)); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("decrypt with tampered header error:", err)
+	}
+}
+
+func TestKeyEnv(t *testing.T) {
+	t.Setenv("EMBED_TEST_KEY", "000102030405060708090a0b0c0d0e0f")
+
+	key, err := KeyEnv("EMBED_TEST_KEY").Key()
+	if err != nil || len(key) != 16 || key[15] != 0x0f {
+		t.Fatal("KeyEnv error:", key, err)
+	}
+
+	if _, err = KeyEnv("EMBED_TEST_KEY_NOT_SET").Key(); err == nil {
+		t.Fatal("KeyEnv not set should fail")
+	}
+
+	t.Setenv("EMBED_TEST_KEY", "short")
+	if _, err = KeyEnv("EMBED_TEST_KEY").Key(); err == nil {
+		t.Fatal("KeyEnv invalid length should fail")
+	}
+}
+
+func TestKeyPassphrase(t *testing.T) {
+	key1, err := KeyPassphrase("passphrase", "salt").Key()
+	if err != nil || len(key1) != 32 {
+		t.Fatal("KeyPassphrase error:", err)
+	}
+
+	key2, err := KeyPassphrase("passphrase", "salt").Key()
+	if err != nil || !bytes.Equal(key1, key2) {
+		t.Fatal("KeyPassphrase not stable:", err)
+	}
+}
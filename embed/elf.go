@@ -0,0 +1,65 @@
+package embed
+
+import (
+	"debug/elf"
+	"io"
+	"strings"
+)
+
+// dataSection ELF文件中存放常量及变量的段，Size常量位于其中
+type dataSection struct {
+	name   string
+	offset int64
+	size   int64
+}
+
+// isDataSection 只读数据段及数据段
+func isDataSection(name string) bool {
+	for _, prefix := range []string{".rodata", ".data", ".noptrdata"} {
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dataSections 解析ELF文件的数据段，非ELF文件返回nil
+func (e *Embed) dataSections(content int64) []dataSection {
+	if e.sections != nil {
+		return e.sections
+	}
+
+	file, err := elf.NewFile(io.NewSectionReader(e.file, 0, content))
+	if err != nil {
+		return nil
+	}
+
+	var sections = make([]dataSection, 0)
+	for _, s := range file.Sections {
+		if s.Type == elf.SHT_NOBITS || !isDataSection(s.Name) {
+			continue
+		}
+
+		if s.Offset+s.Size > uint64(content) {
+			continue
+		}
+
+		sections = append(sections, dataSection{name: s.Name, offset: int64(s.Offset), size: int64(s.Size)})
+	}
+
+	e.sections = sections
+
+	return sections
+}
+
+// sectionOf 偏移所在的数据段名称
+func sectionOf(sections []dataSection, offset int64) string {
+	for _, s := range sections {
+		if offset >= s.offset && offset < s.offset+s.size {
+			return s.name
+		}
+	}
+
+	return ""
+}
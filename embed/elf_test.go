@@ -0,0 +1,36 @@
+package embed
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBlock_Section(t *testing.T) {
+	// 测试程序引用了Size常量，常量位于只读数据段
+	this, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emd, err := Open(this)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emd.Close()
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) == 0 {
+		t.Fatal("executable blocks error:", err, blocks)
+	}
+
+	for _, b := range blocks {
+		if b.Section() == "" || !isDataSection(b.Section()) {
+			t.Fatal("block section error:", b)
+		}
+	}
+
+	// 非ELF文件扫描整个文件
+	if blocks, err = openTestFile(t, Size1KB+"1").Blocks(); err != nil || len(blocks) != 1 || blocks[0].Section() != "" {
+		t.Fatal("raw blocks error:", err, blocks)
+	}
+}
@@ -3,6 +3,8 @@ package embed
 import (
 	"bufio"
 	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -11,11 +13,12 @@ import (
 	"io"
 	"os"
 	"slices"
-	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
-func getOffset(file *os.File, magic []byte) (offsets []int64, err error) {
+func getOffset(file io.ReadSeeker, magic []byte) (offsets []int64, err error) {
 	// 1. 获取文件大小
 	var offset int64
 	if offset, err = file.Seek(0, io.SeekEnd); err != nil {
@@ -55,6 +58,7 @@ func getOffset(file *os.File, magic []byte) (offsets []int64, err error) {
 		if index := bytes.Index(data, magic); index >= 0 {
 			offsets = append(offsets, offset+int64(index))
 			advance = index + len(magic)
+			offset += int64(advance)
 
 			return advance, data[:advance], nil
 		}
@@ -63,11 +67,14 @@ func getOffset(file *os.File, magic []byte) (offsets []int64, err error) {
 			return 0, data, bufio.ErrFinalToken
 		}
 
-		return len(data), nil, nil
+		// 保留末尾不足magic长度的部分，避免magic跨越两次读取时漏查
+		advance = max(len(data)-len(magic)+1, 0)
+		offset += int64(advance)
+
+		return advance, nil, nil
 	})
 
 	for scanner.Scan() {
-		offset += int64(len(scanner.Bytes()))
 	}
 
 	if err = scanner.Err(); err != nil {
@@ -77,16 +84,13 @@ func getOffset(file *os.File, magic []byte) (offsets []int64, err error) {
 	return
 }
 
-func getHeader(file *os.File, offset int64) (_ *Header, err error) {
+func getHeader(file storage, offset int64) (_ *Header, err error) {
 	var h Header
 
 	h.Offset = offset + headerSize
 
-	if _, err = file.Seek(offset, io.SeekStart); err != nil {
-		return
-	}
-
-	if err = binary.Read(file, binary.BigEndian, &h.header); err != nil {
+	// 按偏移读取，不移动共享的文件游标
+	if err = binary.Read(io.NewSectionReader(file, offset, headerSize), binary.BigEndian, &h.header); err != nil {
 		return
 	}
 
@@ -98,7 +102,7 @@ func getHeader(file *os.File, offset int64) (_ *Header, err error) {
 	return &h, nil
 }
 
-func getHeaders(file *os.File, offsets []int64) (headers []Header, err error) {
+func getHeaders(file storage, offsets []int64) (headers []Header, err error) {
 	var h *Header
 	for _, offset := range offsets {
 		if h, err = getHeader(file, offset); err != nil {
@@ -110,55 +114,48 @@ func getHeaders(file *os.File, offsets []int64) (headers []Header, err error) {
 	return
 }
 
-func linkHeaders(headers []Header) {
-	// 按照偏移量排序
-	sort.Slice(headers, func(i, j int) bool {
-		return headers[i].Offset < headers[j].Offset
-	})
-
-	for i, h := range headers {
-		if i >= len(headers)-1 {
-			h.NextOffset = 0
-			break
+func checkHeaders(file storage, headers []Header) []Header {
+	var newHeaders = make([]Header, 0, len(headers))
+	for _, h := range headers {
+		if checked, err := checkHeader(file, h); err == nil {
+			newHeaders = append(newHeaders, checked)
 		}
-
-		headers[i].NextOffset = uint32(h.Offset - headerSize)
 	}
+
+	return newHeaders
 }
 
-func checkHeaders(file *os.File, headers []Header) []Header {
-	var (
-		err        error
-		newHeaders = make([]Header, 0, len(headers))
-	)
-	for _, h := range headers {
-		if h.Reserve1 != 0 || h.Reserve2 != 0 {
-			continue
-		}
+// checkHeader 校验头及数据，返回拒绝的原因
+func checkHeader(file storage, h Header) (_ Header, err error) {
+	// A/B槽以最新提交的槽为准
+	if recovered, ok := recoverSlot(file, h); ok {
+		return recovered, nil
+	}
 
-		// 校验数据容量
-		if _, err = file.Seek(h.Offset+int64(h.DataCap), io.SeekStart); err != nil {
-			continue
-		}
+	if err = h.verifyHeader(); err != nil {
+		return h, err
+	}
 
-		// data为空，则读取文件校验
-		if _, err = file.Seek(h.Offset, io.SeekStart); err != nil {
-			continue
-		}
+	// 未知标志位、版本或非法长度
+	if h.Flags&^knownFlags != 0 {
+		return h, fmt.Errorf("unknown flags: %#x", h.Flags&^knownFlags)
+	}
 
-		var buf = make([]byte, h.DataLen)
-		if h.DataLen > 0 {
-			if _, err = io.ReadFull(file, buf); err != nil {
-				continue
-			}
-		}
+	if err = checkVersion(h.header); err != nil {
+		return h, err
+	}
 
-		if h.Verify(buf) == nil {
-			newHeaders = append(newHeaders, h)
-		}
+	if h.DataLen > h.capacity() {
+		return h, errors.New("invalid data length")
 	}
 
-	return newHeaders
+	// data为空，则读取文件校验
+	var buf = make([]byte, h.DataLen)
+	if _, err = file.ReadAt(buf, h.Offset); err != nil {
+		return h, fmt.Errorf("read data: %w", err)
+	}
+
+	return h, h.Verify(buf)
 }
 
 func initHeaders(headers []Header) []Header {
@@ -172,7 +169,7 @@ func initHeaders(headers []Header) []Header {
 	return headers
 }
 
-func writeHeaders(file *os.File, headers []Header) (err error) {
+func writeHeaders(file storage, headers []Header) (err error) {
 	var data []byte
 
 	for _, h := range headers {
@@ -188,13 +185,8 @@ func writeHeaders(file *os.File, headers []Header) (err error) {
 			return
 		}
 
-		// 移动游标
-		if _, err = file.Seek(h.Offset-headerSize, io.SeekStart); err != nil {
-			return
-		}
-
-		// 刷写落盘
-		if _, err = file.Write(data); err != nil {
+		// 按偏移写入
+		if _, err = file.WriteAt(data, h.Offset-headerSize); err != nil {
 			return
 		}
 	}
@@ -202,34 +194,34 @@ func writeHeaders(file *os.File, headers []Header) (err error) {
 	return
 }
 
-func readHeaders(file *os.File) (headers []Header, err error) {
-	// 重置文件游标
-	if _, err = file.Seek(0, io.SeekStart); err != nil {
-		return
-	}
+func (e *Embed) readHeaders() (headers []Header, err error) {
+	// 优先使用索引，否则扫描文件
+	if e.indexed() {
+		var offsets = make([]int64, len(e.index))
+		for i, entry := range e.index {
+			offsets[i] = entry.Offset
+		}
 
-	// 获取偏移量
-	offsets, err := getOffset(file, []byte(magic))
-	if err != nil {
-		return
+		headers, err = getHeaders(e.file, offsets)
+	} else {
+		_, headers, err = e.scanHeaders()
 	}
 
-	// 解析头
-	if headers, err = getHeaders(file, offsets); err != nil {
+	if err != nil {
 		return
 	}
 
 	// 校验头，过滤掉非法头
-	headers = checkHeaders(file, headers)
+	headers = checkHeaders(e.file, headers)
 
-	// TODO 这里定义时也会调用，可能导致下面写入失败（text file busy），导致运行中断
 	return
+}
 
+// syncHeaders 初始化头，有变化时写入文件
+// TODO 这里定义时也会调用，可能导致下面写入失败（text file busy），导致运行中断，暂不在readHeaders中调用
+func syncHeaders(file storage, headers []Header) (err error) {
 	var cloneHeaders = slices.Clone(headers)
 
-	// 建立连接
-	linkHeaders(headers)
-
 	// 初始化
 	headers = initHeaders(headers)
 
@@ -251,143 +243,897 @@ func readHeaders(file *os.File) (headers []Header, err error) {
 	return
 }
 
+// getName 读取块数据后的名称标签
+func getName(file storage, h Header) (name string, err error) {
+	var buf = make([]byte, len(NameTag)+maxNameLen+1)
+
+	n, err := file.ReadAt(buf, h.Offset+int64(h.DataCap))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return
+	}
+
+	return parseName(buf[:n]), nil
+}
+
 var (
-	embed  *Embed
-	malloc = make(map[string]bool)
+	malloc     = make(map[string]*Block) // 已分配的块，更新文件时同步切换
+	mallocLock sync.Mutex                // 保护malloc
 )
 
-func init() {
-	var err error
+type Block struct {
+	file      storage
+	name      string
+	header    Header
+	compress  Compression        // 写入时使用的压缩算法
+	encrypt   Encryption         // 写入时使用的加密算法
+	key       KeyProvider        // 加解密密钥
+	signer    ed25519.PrivateKey // 写入时使用的签名私钥
+	verifier  ed25519.PublicKey  // 读取时校验签名的公钥
+	atomic    bool               // 写入时使用A/B槽
+	revisions int                // 写入时版本环的槽数，0表示保持原有布局
+	chain     []Header           // 链中的后续块
+	section   string             // 所在的ELF数据段
+	lock      *sync.RWMutex      // 同一文件的块共用，读取持有读锁，写入持有写锁
+	mapping   *mapping           // 同一文件的块共用的内存映射
+}
 
-	this, err := os.Executable()
-	if err != nil {
-		panic(err)
+// rlock 加读锁，返回解锁函数，未关联文件的块不加锁
+func (b *Block) rlock() func() {
+	if b.lock == nil {
+		return func() {}
 	}
 
-	if embed, err = Open(this); err != nil {
-		panic(err)
-	}
+	b.lock.RLock()
+
+	return b.lock.RUnlock
 }
 
-type Block struct {
-	file   *os.File
-	header Header
+// wlock 加写锁，返回解锁函数
+func (b *Block) wlock() func() {
+	if b.lock == nil {
+		return func() {}
+	}
+
+	b.lock.Lock()
+
+	return b.lock.Unlock
 }
 
-func (b Block) String() string {
-	data, _ := json.Marshal(b.header.header)
+func (b *Block) String() string {
+	defer b.rlock()()
+
+	data, _ := json.Marshal(struct {
+		Name    string `json:",omitempty"`
+		Section string `json:",omitempty"`
+		header
+	}{
+		Name:    b.name,
+		Section: b.section,
+		header:  b.header.header,
+	})
 	return string(data)
 }
 
-func (b Block) Len() uint32 {
-	return b.header.DataLen
+// Name returns the name tagged after the block, empty for unnamed blocks.
+func (b *Block) Name() string {
+	return b.name
+}
+
+// Section returns the ELF section holding the block, such as .rodata, or
+// an empty string for other files.
+func (b *Block) Section() string {
+	return b.section
 }
 
-func (b Block) Cap() uint32 {
-	return b.header.DataCap
+// ModTime returns the time of the last write, zero for blocks never written.
+func (b *Block) ModTime() time.Time {
+	defer b.rlock()()
+
+	if b.header.UpdateTime == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(b.header.UpdateTime, 0)
+}
+
+// Len returns the logical data length, which is the length before
+// compression and encryption.
+func (b *Block) Len() uint32 {
+	defer b.rlock()()
+
+	return b.len()
+}
+
+func (b Block) len() uint32 {
+	if b.header.encoded() {
+		return b.header.RawLen
+	}
+
+	return uint32(b.stored().Size())
 }
 
-func (b Block) Read(buf []byte) (n int, err error) {
+// StoredLen returns the length of data stored in the block.
+func (b *Block) StoredLen() uint32 {
+	defer b.rlock()()
+
+	return uint32(b.stored().Size())
+}
+
+// Cap returns the capacity for writes, which is the capacity of a slot for
+// atomic blocks, or the sum of all capacities for chains.
+func (b *Block) Cap() uint32 {
+	defer b.rlock()()
+
+	return b.cap()
+}
+
+func (b Block) cap() uint32 {
+	if b.atomic {
+		var h = b.layout()
+		return slotCap(&h)
+	}
+
+	return uint32(b.target().size())
+}
+
+// CheckWrite reports whether a write storing size bytes, after
+// compression, encryption and signing, would fail before anything is
+// written: no key is set for an encrypted write, the data does not fit
+// Cap, which is the slot capacity of atomic writes, or an atomic write is
+// not possible because the block is chained or its data overlaps every
+// slot.
+func (b *Block) CheckWrite(size uint32) (err error) {
+	defer b.rlock()()
+
+	if b.encrypt != EncryptNone && b.key == nil {
+		return ErrNoKey
+	}
+
+	if err = b.checkAtomic(); err != nil {
+		return
+	}
+
+	if size > b.cap() {
+		return fmt.Errorf("data too large: %d > %d", size, b.cap())
+	}
+
+	return
+}
+
+// Atomic reports whether the block data is stored in A/B slots or a
+// revision ring.
+func (b *Block) Atomic() bool {
+	defer b.rlock()()
+
+	return b.header.slotted()
+}
+
+// SetAtomic sets whether later writes use A/B slots. Atomic writes go to
+// the inactive slot and commit by its slot header, so a crash at any time
+// leaves either the old or the new data. The capacity is halved. The first
+// atomic write of a block goes to a slot clear of the current data, and
+// fails when the data is longer than about half of the capacity.
+func (b *Block) SetAtomic(atomic bool) {
+	defer b.wlock()()
+
+	b.atomic = atomic
+}
+
+// offset 读取数据的偏移量
+func (b Block) offset() int64 {
+	if b.header.slotted() {
+		return slotData(&b.header, b.header.slot)
+	}
+
+	return b.header.Offset
+}
+
+// layout 写入时使用的槽布局
+func (b Block) layout() Header {
+	var h = b.header
+	if b.revisions > 0 {
+		h.Flags = h.Flags&^flagSlots | uint32(b.revisions)<<12
+	} else if !h.slotted() {
+		h.Flags = h.Flags&^flagSlots | 2<<12
+	}
+
+	return h
+}
+
+// inactiveSlot 写入的槽：无效的槽或提交代数最小的槽，首次使用槽或槽布局改变时
+// 写入不与当前数据重叠的槽，没有时为-1
+func (b Block) inactiveSlot() int {
+	var h = b.layout()
+	if !b.header.slotted() {
+		return clearSlot(&h, b.header.Offset, b.header.Offset+int64(b.header.DataLen))
+	}
+
+	if h.Flags&flagSlots != b.header.Flags&flagSlots {
+		return clearSlot(&h, slotOffset(&b.header, b.header.slot), b.offset()+int64(b.header.DataLen))
+	}
+
+	var (
+		index  = -1
+		oldest uint32
+	)
+	for i, s := range readSlots(b.file, &h) {
+		if i == b.header.slot {
+			continue
+		}
+
+		if s == nil {
+			return i
+		}
+
+		if index == -1 || s.Generation < oldest {
+			index, oldest = i, s.Generation
+		}
+	}
+
+	return index
+}
+
+// clearSlot 与区域[start, end)不重叠的槽，优先第二个槽，没有时为-1
+func clearSlot(h *Header, start, end int64) int {
+	var n = h.slots()
+	for i := range n {
+		var (
+			index  = (i + 1) % n
+			offset = slotOffset(h, index)
+		)
+		if offset >= end || slotData(h, index)+int64(slotCap(h)) <= start {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// checkAtomic 校验原子写入：块链不支持，需有不与当前数据重叠的槽
+func (b Block) checkAtomic() error {
+	if !b.atomic {
+		return nil
+	}
+
+	if b.chained() {
+		return errAtomicChain
+	}
+
+	if b.inactiveSlot() == -1 {
+		return errSlotLayout
+	}
+
+	return nil
+}
+
+// target 写入数据的区域
+func (b Block) target() segments {
+	if b.atomic {
+		var h = b.layout()
+		return segments{file: b.file, list: []segment{{slotData(&h, b.inactiveSlot()), int64(slotCap(&h))}}}
+	}
+
+	var list = []segment{{b.header.Offset, int64(b.header.capacity())}}
+	for _, h := range b.chain {
+		list = append(list, segment{h.Offset, int64(h.capacity())})
+	}
+
+	return segments{file: b.file, list: list}
+}
+
+// Compression returns the compression algorithm of the stored data.
+func (b *Block) Compression() Compression {
+	defer b.rlock()()
+
+	return b.header.compression()
+}
+
+// SetCompression sets the compression algorithm used by later writes.
+func (b *Block) SetCompression(c Compression) (err error) {
+	if err = c.check(); err != nil {
+		return
+	}
+
+	defer b.wlock()()
+
+	b.compress = c
+
+	return
+}
+
+// Encryption returns the encryption algorithm of the stored data.
+func (b *Block) Encryption() Encryption {
+	defer b.rlock()()
+
+	return b.header.encryption()
+}
+
+// SetEncryption sets the encryption algorithm used by later writes, the
+// key must be set by SetKey. The algorithm is recorded in the header flags
+// and the nonce in the ExtNonce extension of version 2 blocks, see Upgrade.
+// Version 1 blocks, which have no room for it, and atomic writes, where each
+// slot needs its own nonce, store the nonce before the ciphertext. The raw
+// length, compression and encryption of the header are authenticated with
+// the data, so tampering with them fails decryption.
+func (b *Block) SetEncryption(e Encryption) (err error) {
+	if err = e.check(); err != nil {
+		return
+	}
+
+	defer b.wlock()()
+
+	b.encrypt = e
+
+	return
+}
+
+// SetKey sets the key provider used to encrypt and decrypt data.
+func (b *Block) SetKey(key KeyProvider) {
+	defer b.wlock()()
+
+	b.key = key
+}
+
+// Signed reports whether the stored data carries a signature.
+func (b *Block) Signed() bool {
+	defer b.rlock()()
+
+	return b.header.signed()
+}
+
+// SetSigner sets the private key signing the data of later writes, nil
+// disables signing. The signature covers the stored data together with the
+// raw length and the compression and encryption of the header.
+func (b *Block) SetSigner(key ed25519.PrivateKey) {
+	defer b.wlock()()
+
+	b.signer = key
+}
+
+// SetVerifier sets the public key checking the signature on every read,
+// unsigned or tampered data is rejected. nil disables the check.
+func (b *Block) SetVerifier(key ed25519.PublicKey) {
+	defer b.wlock()()
+
+	b.verifier = key
+}
+
+// Verify checks the signature of the stored data against the public key.
+func (b *Block) Verify(key ed25519.PublicKey) (err error) {
+	defer b.rlock()()
+
+	return b.verify(key)
+}
+
+func (b Block) verify(key ed25519.PublicKey) (err error) {
+	if !b.header.signed() {
+		return ErrUnsigned
+	}
+
+	var (
+		stored = b.stored()
+		size   = stored.Size() - ed25519.SignatureSize
+	)
+	if size < 0 {
+		return ErrSignature
+	}
+
+	// 流式计算摘要，签名包含存放在头中的nonce
+	var (
+		digest    = sha512.New()
+		signature = make([]byte, ed25519.SignatureSize)
+	)
+	digest.Write(b.headerNonce())
+	if _, err = io.Copy(digest, io.NewSectionReader(stored, 0, size)); err != nil {
+		return
+	}
+
+	if _, err = stored.ReadAt(signature, size); err != nil {
+		return
+	}
+
+	return verifySignature(key, signedDigest(digest, b.header.RawLen, b.header.Flags), signature)
+}
+
+func (b *Block) Read(buf []byte) (n int, err error) {
 	if len(buf) == 0 {
 		return
 	}
 
-	if uint32(len(buf)) > b.header.DataLen {
-		buf = buf[:b.header.DataLen]
+	defer b.rlock()()
+
+	if size := b.len(); uint32(len(buf)) > size {
+		buf = buf[:size]
+	}
+
+	if n, err = b.read(buf, 0); err == io.EOF && n == len(buf) {
+		err = nil
 	}
 
-	if _, err = b.file.Seek(b.header.Offset, io.SeekStart); err != nil {
+	return
+}
+
+// ReadAt implements io.ReaderAt, reading the block data starting at off.
+// Compressed or encrypted data is decoded from the beginning on every
+// call, use NewReader for sequential reads.
+func (b *Block) ReadAt(buf []byte, off int64) (n int, err error) {
+	defer b.rlock()()
+
+	return b.read(buf, off)
+}
+
+// read 读取数据，调用方持有读锁
+func (b Block) read(buf []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if b.direct() {
+		return b.readAt(buf, off)
+	}
+
+	data, err := b.decode()
+	if err != nil {
 		return
 	}
 
-	return b.file.Read(buf)
+	return bytes.NewReader(data).ReadAt(buf, off)
+}
+
+// direct 存储的即为原始数据，可直接读取
+func (b Block) direct() bool {
+	return !b.header.encoded() && b.verifier == nil
+}
+
+// readAt 读取存储的原始数据
+func (b Block) readAt(buf []byte, off int64) (n int, err error) {
+	return b.stored().ReadAt(buf, off)
+}
+
+// stored 存储的数据，块链时依次拼接各块的数据
+func (b Block) stored() *io.SectionReader {
+	var list = []segment{{b.offset(), int64(b.header.DataLen)}}
+	for _, h := range b.chain {
+		list = append(list, segment{h.Offset, int64(h.DataLen)})
+	}
+
+	var s = segments{file: b.file, list: list}
+
+	return io.NewSectionReader(s, 0, s.size())
 }
 
-func (b Block) Write(data []byte) (n int, err error) {
+// decode 读取全部数据，校验签名，解密并解压
+func (b Block) decode() (data []byte, err error) {
+	var (
+		section           = b.stored()
+		stored  io.Reader = section
+	)
+
+	if b.verifier != nil {
+		if err = b.verify(b.verifier); err != nil {
+			return
+		}
+	}
+
+	// 去掉签名
+	if b.header.signed() {
+		stored = io.LimitReader(stored, section.Size()-ed25519.SignatureSize)
+	}
+
+	if encryption := b.header.encryption(); encryption != EncryptNone {
+		if data, err = io.ReadAll(stored); err != nil {
+			return
+		}
+
+		// 存放在头中的nonce
+		if nonce := b.headerNonce(); nonce != nil {
+			data = append(nonce[:len(nonce):len(nonce)], data...)
+		}
+
+		if data, err = encryption.decrypt(b.key, data, dataHeader(b.header.RawLen, b.header.Flags)); err != nil {
+			return
+		}
+
+		stored = bytes.NewReader(data)
+	}
+
+	reader, err := b.header.compression().NewReader(stored)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	data = make([]byte, b.len())
+	if _, err = io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+
+	// 解码的数据需与原始大小一致
+	if _, err = io.ReadFull(reader, make([]byte, 1)); err != io.EOF {
+		return nil, errors.New("decoded data longer than raw length")
+	}
+
+	return data, nil
+}
+
+// NewReader returns a reader of the block data, which implements
+// io.Reader, io.ReaderAt and io.Seeker. Compressed or encrypted data is
+// decoded into memory once. The reader keeps reading the data of the block
+// at the time NewReader is called.
+func (b *Block) NewReader() *io.SectionReader {
+	defer b.rlock()()
+
+	// 复制块，后续读取仍与写入互斥
+	var clone = *b
+	if clone.direct() {
+		return io.NewSectionReader(&clone, 0, int64(clone.len()))
+	}
+
+	data, err := clone.decode()
+	if err != nil {
+		return io.NewSectionReader(errReaderAt{err: err}, 0, int64(clone.len()))
+	}
+
+	return io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+}
+
+func (b *Block) Write(data []byte) (n int, err error) {
 	if len(data) == 0 {
 		return
 	}
 
+	defer b.wlock()()
+
+	return b.write(data)
+}
+
+// write 写入数据，调用方持有写锁
+func (b *Block) write(data []byte) (n int, err error) {
+	if err = b.checkAtomic(); err != nil {
+		return
+	}
+
+	var size = uint32(len(data))
+
+	// 压缩数据
+	if data, err = b.compress.encode(data); err != nil {
+		return
+	}
+
+	// 加密数据
+	if data, err = b.encrypt.encrypt(b.key, data, dataHeader(size, b.flags())); err != nil {
+		return
+	}
+
+	// 追加签名
+	if b.signer != nil {
+		var (
+			digest    = sha512.New()
+			signature []byte
+		)
+		digest.Write(data)
+		if signature, err = sign(b.signer, signedDigest(digest, size, b.flags())); err != nil {
+			return
+		}
+
+		data = append(data[:len(data):len(data)], signature...)
+	}
+
+	// nonce移入头的扩展区
+	ext, data, err := b.splitNonce(b.encrypt, data)
+	if err != nil {
+		return
+	}
+
 	// 校验数据大小
-	if uint32(len(data)) > b.header.DataCap {
+	if uint32(len(data)) > b.cap() {
 		return 0, fmt.Errorf("data too large")
 	}
 
-	// 移动游标
-	if _, err = b.file.Seek(b.header.Offset, io.SeekStart); err != nil {
-		fmt.Println("seek error:???", err)
-		return
+	if ext != nil {
+		if err = b.writeExtensions(ext); err != nil {
+			return
+		}
 	}
 
 	// 写入数据
-	if _, err = b.file.Write(data); err != nil {
+	if _, err = b.target().WriteAt(data, 0); err != nil {
 		return
 	}
 
 	// 更新头
-	b.header.DataLen = uint32(len(data))
-	b.header.DataCRC32 = crc32.ChecksumIEEE(data)
-	b.header.UpdateTime = time.Now().Unix()
-	if b.header.CreateTime == 0 {
-		b.header.CreateTime = time.Now().Unix()
+	if err = b.commit(uint32(len(data)), crc32.ChecksumIEEE(data), size); err != nil {
+		return
 	}
 
-	// 计算头crc32
-	var crcData []byte
-	b.header.CRC32 = 0
-	if crcData, err = b.header.Encode(); err != nil {
+	return int(size), nil
+}
+
+// WriteAt implements io.WriterAt, writing data into the block at off and
+// extending the block length when needed. Bytes between the old length
+// and off keep their previous content, or are zero for compressed and
+// encrypted blocks, which are decoded and rewritten entirely.
+func (b *Block) WriteAt(data []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	defer b.wlock()()
+
+	// 压缩、加密、签名、A/B槽或块链的数据需整体重写
+	if b.header.encoded() || b.header.slotted() || b.encoding() || b.atomic || b.chained() {
+		return b.rewriteAt(data, off)
+	}
+
+	// 校验数据大小
+	var end = off + int64(len(data))
+	if end > int64(b.header.capacity()) {
+		return 0, fmt.Errorf("data too large")
+	}
+
+	// 写入数据
+	if _, err = b.file.WriteAt(data, b.header.Offset+off); err != nil {
+		return
+	}
+
+	var size = max(end, int64(b.header.DataLen))
+
+	// 流式计算数据crc32
+	var hash = crc32.NewIEEE()
+	if _, err = io.Copy(hash, io.NewSectionReader(b.file, b.header.Offset, size)); err != nil {
 		return
 	}
-	b.header.CRC32 = crc32.ChecksumIEEE(crcData)
 
-	// 校验头
-	if err = b.header.Verify(data); err != nil {
+	// 更新头
+	if err = b.commit(uint32(size), hash.Sum32(), uint32(size)); err != nil {
+		return
+	}
+
+	return len(data), nil
+}
+
+// encoding 写入时需要压缩、加密或签名
+func (b *Block) encoding() bool {
+	return b.compress != CompressNone || b.encrypt != EncryptNone || b.signer != nil
+}
+
+// rewriteAt 读取全部数据，修改后整体写入
+func (b *Block) rewriteAt(data []byte, off int64) (n int, err error) {
+	buf, err := b.decode()
+	if err != nil {
 		return
 	}
 
-	// 序列化头
-	if data, err = b.header.Encode(); err != nil {
+	if end := off + int64(len(data)); end > int64(len(buf)) {
+		buf = append(buf, make([]byte, end-int64(len(buf)))...)
+	}
+
+	copy(buf[off:], data)
+
+	if _, err = b.write(buf); err != nil {
 		return
 	}
 
-	// 移动游标
-	if _, err = b.file.Seek(b.header.Offset-headerSize, io.SeekStart); err != nil {
+	return len(data), nil
+}
+
+// NewWriter returns a writer replacing the block data with everything
+// written to it, the header is updated when the writer is closed. Data of
+// encrypted blocks is buffered in memory until closed. Reads are excluded
+// from each write, but may see partially written data of blocks which are
+// not atomic until the writer is closed.
+func (b *Block) NewWriter() *Writer {
+	defer b.rlock()()
+
+	var w = &Writer{
+		block: b,
+		hash:  crc32.NewIEEE(),
+	}
+
+	if w.err = b.checkAtomic(); w.err != nil {
+		return w
+	}
+
+	w.target = b.target()
+
+	var stored io.Writer = storedWriter{w: w}
+	if b.encrypt != EncryptNone {
+		if b.key == nil {
+			w.err = ErrNoKey
+			return w
+		}
+
+		w.buffer = new(bytes.Buffer)
+		stored = w.buffer
+	}
+
+	if b.signer != nil {
+		w.digest = sha512.New()
+	}
+
+	w.encoder, w.err = b.compress.NewWriter(stored)
+
+	return w
+}
+
+// commitSlot 提交写入的槽
+func (b *Block) commitSlot(h *Header) (err error) {
+	var (
+		layout = b.layout()
+		index  = b.inactiveSlot()
+		s      = slot{
+			Generation: b.header.generation + 1,
+			Time:       h.UpdateTime,
+			Flags:      h.Flags & dataFlags,
+			DataLen:    h.DataLen,
+			RawLen:     h.RawLen,
+			DataCRC32:  h.DataCRC32,
+		}
+	)
+
+	// 数据落盘
+	if err = b.file.Sync(); err != nil {
 		return
 	}
 
-	// 写入头
-	if _, err = b.file.Write(data); err != nil {
+	// 槽头落盘即提交
+	if err = writeSlot(b.file, &layout, index, s); err != nil {
 		return
 	}
 
-	// 刷写落盘
 	if err = b.file.Sync(); err != nil {
 		return
 	}
 
+	h.Flags |= layout.Flags & flagSlots
+	applySlot(h, index, s)
+
+	return
+}
+
+// flags 写入的数据标志位
+func (b *Block) flags() (flags uint32) {
+	if flags = uint32(b.compress) | uint32(b.encrypt)<<4; b.signer != nil {
+		flags |= flagSigned
+	}
+
+	return
+}
+
+// commit 更新数据长度、crc32及原始大小，重新计算头crc32并写入文件
+func (b *Block) commit(stored, sum, size uint32) error {
+	return b.commitFlags(stored, sum, size, b.flags())
+}
+
+// commitFlags 以指定的数据标志位提交
+func (b *Block) commitFlags(stored, sum, size, flags uint32) (err error) {
+	var h = b.header
+
+	h.DataLen = stored
+	h.DataCRC32 = sum
+	h.Flags = h.Flags&^dataFlags | flags
+	h.RawLen = 0
+	if h.encoded() {
+		h.RawLen = size
+	}
+	h.UpdateTime = time.Now().Unix()
+	if h.CreateTime == 0 {
+		h.CreateTime = h.UpdateTime
+	}
+
+	// A/B槽：数据落盘后写入槽头提交，再更新头
+	h.Flags &^= flagSlotted | flagSlotB | flagChained | flagSlots
+	h.generation, h.slot = 0, 0
+	h.NextOffset = 0
+	if b.atomic {
+		if err = b.commitSlot(&h); err != nil {
+			return
+		}
+	}
+
+	// 块链：划分数据并写入后续块的头，再更新头块
+	var chain []Header
+	if b.chained() {
+		if chain, err = b.link(&h, stored); err != nil {
+			return
+		}
+	}
+
+	// 写入头并落盘
+	if err = b.writeHeader(&h); err != nil {
+		return
+	}
+
+	b.header, b.chain = h, chain
+
 	return
 }
 
+// Embed is a file holding blocks. Blocks, Payloads and FS, and the reads
+// and writes of the blocks, are safe for concurrent use, the blocks of a
+// file share a lock excluding reads from writes. Other methods modifying
+// the file must not run concurrently with them.
 type Embed struct {
-	file *os.File
+	lock     *sync.RWMutex // 保护file及index，与块共用
+	mapping  *mapping      // 只读打开的文件的内存映射，与块共用
+	file     storage
+	name     string        // 文件名
+	flag     int           // 打开方式
+	index    []indexEntry  // 已校验的块索引
+	sections []dataSection // ELF数据段，非ELF文件为空
+	flock    int           // 持有的文件锁，见Lock
+}
+
+// newEmbed 创建打开的文件
+func newEmbed(file storage, name string, flag int) *Embed {
+	return &Embed{lock: new(sync.RWMutex), mapping: newMapping(file, flag), file: file, name: name, flag: flag}
 }
 
 func (e *Embed) Blocks() (blocks []Block, err error) {
-	headers, err := readHeaders(e.file)
+	// 读取时可能加载索引
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	headers, err := e.readHeaders()
 	if err != nil {
 		return
 	}
 
-	for _, h := range headers {
+	// 索引中记录了名称
+	var names = make(map[int64]string, len(e.index))
+	for _, entry := range e.index {
+		names[entry.Offset+headerSize] = entry.Name
+	}
+
+	// ELF数据段，用于标记块所在的段
+	var sections []dataSection
+	if _, content, err := readTrailers(e.file); err == nil {
+		sections = e.dataSections(content)
+	}
+
+	heads, chains := linkChains(headers)
+	for i, h := range heads {
+		name, ok := names[h.Offset]
+		if !ok {
+			if name, err = blockName(e.file, h); err != nil {
+				return
+			}
+		}
+
 		blocks = append(blocks, Block{
-			file:   e.file,
-			header: h,
+			file:     e.file,
+			name:     name,
+			header:   h,
+			compress: h.compression(),
+			encrypt:  h.encryption(),
+			atomic:   h.slotted(),
+			chain:    chains[i],
+			section:  sectionOf(sections, h.Offset-headerSize),
+			lock:     e.lock,
+			mapping:  e.mapping,
 		})
 	}
 
 	return
 }
 
+// Lookup returns the block tagged with name.
+func (e *Embed) Lookup(name string) (_ *Block, err error) {
+	blocks, err := e.Blocks()
+	if err != nil {
+		return
+	}
+
+	for _, b := range blocks {
+		if b.name == name {
+			return &b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("block %s not found", name)
+}
+
+// Close closes the file, slices returned by Block.Bytes stay valid.
 func (e *Embed) Close() (err error) {
 	return e.file.Close()
 }
@@ -395,12 +1141,8 @@ func (e *Embed) Close() (err error) {
 func Open(filename string) (embed *Embed, err error) {
 	var flag = os.O_RDWR
 
-	this, err := os.Executable()
-	if err != nil {
-		return
-	}
-
-	if filename == this {
+	// 正在运行的可执行文件只读打开，避免text file busy
+	if this, err := os.Executable(); err == nil && filename == this {
 		flag = os.O_RDONLY
 	}
 
@@ -409,25 +1151,43 @@ func Open(filename string) (embed *Embed, err error) {
 		return
 	}
 
-	return &Embed{file: file}, err
+	return newEmbed(file, filename, flag), err
 }
 
+// Blocks returns the blocks of the running executable, or of the source
+// set by SetSource.
 func Blocks() (blocks []Block, err error) {
-	return embed.Blocks()
+	emd, err := self()
+	if err != nil {
+		return
+	}
+
+	return emd.Blocks()
 }
 
-func Export(filename string, block Block) (err error) {
-	var buf = make([]byte, block.header.DataLen)
+// Lookup returns the block tagged with name of the running executable, or
+// of the source set by SetSource.
+func Lookup(name string) (_ *Block, err error) {
+	emd, err := self()
+	if err != nil {
+		return
+	}
 
-	if _, err = block.Read(buf); err != nil {
+	return emd.Lookup(name)
+}
+
+func Export(filename string, block Block) (err error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
 		return
 	}
+	defer file.Close()
 
-	if err = os.WriteFile(filename, buf, 0644); err != nil {
+	if _, err = io.Copy(file, block.NewReader()); err != nil {
 		return
 	}
 
-	return
+	return file.Close()
 }
 
 func Malloc(size Size) (_ *Block, err error) {
@@ -435,7 +1195,11 @@ func Malloc(size Size) (_ *Block, err error) {
 		return nil, errors.New("invalid size")
 	}
 
-	if malloc[string(size)] {
+	mallocLock.Lock()
+	defer mallocLock.Unlock()
+
+	var hash = md5sum(stringBytes(string(size)))
+	if malloc[hash] != nil {
 		return nil, errors.New("block already malloced")
 	}
 
@@ -444,52 +1208,57 @@ func Malloc(size Size) (_ *Block, err error) {
 		return
 	}
 
-	for _, b := range blocks {
-		//if data, err = b.header.Encode(); err != nil {
-		//	return
-		//}
+	block, err := findBlock(blocks, size)
+	if err != nil {
+		return
+	}
 
-		// 数据长度
-		var baseSize = headerSize + b.header.DataCap
-		if uint32(len(size)) < baseSize {
-			continue
-		}
+	malloc[hash] = block
 
-		// 匹配头
-		//if !bytes.Equal(data, []byte(size[:len(data)])) {
-		//	continue
-		//}
+	return block, nil
+}
 
-		// 匹配尾
-		if uint32(len(size)) > baseSize {
-			var (
-				suffix = size[baseSize:]
-				buffer = make([]byte, len(suffix))
-			)
+// findBlock 查找由size预留的块
+func findBlock(blocks []Block, size Size) (_ *Block, err error) {
+	for _, b := range blocks {
+		var ok bool
+		if ok, err = matchSize(b.file, b.header, size); err != nil {
+			return
+		}
 
-			if _, err = b.file.Seek(b.header.Offset+int64(b.header.DataCap), io.SeekStart); err != nil {
-				return
-			}
+		if ok {
+			return &b, nil
+		}
+	}
 
-			if _, err = io.ReadFull(b.file, buffer); err != nil {
-				return
-			}
+	return nil, errors.New("invalid size")
+}
 
-			if !bytes.Equal([]byte(suffix), buffer) {
-				continue
-			}
-		}
+// matchSize 校验块是否由size预留：数据容量需一致，size超出的部分需与文件一致
+func matchSize(file storage, h Header, size Size) (ok bool, err error) {
+	// 数据长度
+	var baseSize = headerSize + h.DataCap
+	if uint32(len(size)) < baseSize {
+		return
+	}
 
+	// 匹配尾
+	if uint32(len(size)) > baseSize {
 		var (
-			data = stringBytes(string(size))
-			hash = md5sum(data)
+			suffix = size[baseSize:]
+			buffer = make([]byte, len(suffix))
 		)
-		malloc[hash] = true
 
-		return &b, nil
+		if _, err = file.ReadAt(buffer, h.Offset+int64(h.DataCap)); err != nil {
+			return
+		}
+
+		if !bytes.Equal([]byte(suffix), buffer) {
+			return
+		}
 	}
 
-	return nil, errors.New("invalid size")
+	return true, nil
 }
 
 func MustMalloc(size Size) *Block {
@@ -501,6 +1270,51 @@ func MustMalloc(size Size) *Block {
 	return block
 }
 
+// MallocNamed allocates the block tagged with name, the size must be a
+// constant expression ending with the tag. Names are up to 64 printable
+// characters and are not made only of digits, which are read as block
+// numbers by the embed command, for example:
+//
+//	var config = embed.MustMallocNamed("config", embed.Size4KB+embed.NameTag+"config\x00")
+func MallocNamed(name string, size Size) (_ *Block, err error) {
+	if err = checkName(name); err != nil {
+		return
+	}
+
+	if !strings.HasSuffix(string(size), NameTag+name+"\x00") {
+		return nil, fmt.Errorf("size is not tagged with name %s", name)
+	}
+
+	return Malloc(size)
+}
+
+func MustMallocNamed(name string, size Size) *Block {
+	block, err := MallocNamed(name, size)
+	if err != nil {
+		panic(err)
+	}
+
+	return block
+}
+
+// MallocBytes allocates the block reserved by size and returns its data,
+// see Block.Bytes. The data is mapped from the running executable without
+// copying, which suits large read-only assets.
 func MallocBytes(size Size) (buf []byte, err error) {
-	return
+	block, err := Malloc(size)
+	if err != nil {
+		return
+	}
+
+	return block.Bytes()
+}
+
+// MustMallocBytes is like MallocBytes but panics on error.
+func MustMallocBytes(size Size) []byte {
+	buf, err := MallocBytes(size)
+	if err != nil {
+		panic(err)
+	}
+
+	return buf
 }
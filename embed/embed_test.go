@@ -0,0 +1,422 @@
+package embed
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// openTestFile 创建包含指定块的临时文件并打开
+func openTestFile(t *testing.T, sizes ...Size) *Embed {
+	t.Helper()
+
+	var data = []byte("\x7fELF test file prefix")
+	for _, size := range sizes {
+		data = append(data, size...)
+		data = append(data, "\x00padding\x00"...)
+	}
+
+	var filename = filepath.Join(t.TempDir(), "test.bin")
+	if err := os.WriteFile(filename, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	emd, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = emd.Close()
+	})
+
+	return emd
+}
+
+func TestEmbed_Lookup(t *testing.T) {
+	var emd = openTestFile(t,
+		Size1KB+"1",
+		Size1KB+NameTag+"config\x00",
+		Size2KB+NameTag+"license\x00",
+	)
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 3 || blocks[0].Name() != "" || blocks[1].Name() != "config" || blocks[2].Name() != "license" {
+		t.Fatal("blocks name error:", blocks)
+	}
+
+	block, err := emd.Lookup("license")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Cap() != 2048 {
+		t.Fatal("lookup block error:", block)
+	}
+
+	if _, err = emd.Lookup("unknown"); err == nil {
+		t.Fatal("lookup unknown block should fail")
+	}
+}
+
+func TestGetOffset(t *testing.T) {
+	var (
+		data    = make([]byte, 1024*1024*5)
+		offsets = []int64{0, 4095, 65535, 1024*1024*5 - int64(len(magic))}
+	)
+
+	for _, offset := range offsets {
+		copy(data[offset:], magic)
+	}
+
+	var filename = filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	result, err := getOffset(file, []byte(magic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(result, offsets) {
+		t.Fatal("getOffset error:", result)
+	}
+}
+
+func TestBlock_ReadAt(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block = &blocks[0]
+	if _, err = block.Write([]byte("Hello World")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = make([]byte, 5)
+	if n, err := block.ReadAt(buf, 6); err != nil || n != 5 || string(buf) != "World" {
+		t.Fatal("ReadAt error:", n, err, string(buf))
+	}
+
+	if n, err := block.ReadAt(buf, 8); err != io.EOF || n != 3 || string(buf[:n]) != "rld" {
+		t.Fatal("ReadAt eof error:", n, err)
+	}
+
+	var reader = block.NewReader()
+	if _, err = reader.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil || string(data) != "World" {
+		t.Fatal("NewReader error:", err, string(data))
+	}
+}
+
+func TestBlock_WriteAt(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block = &blocks[0]
+	if _, err = block.Write([]byte("Hello World")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.WriteAt([]byte("Gopher"), 6); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.WriteAt(make([]byte, 1), 1024); err == nil {
+		t.Fatal("WriteAt beyond capacity should fail")
+	}
+
+	// 重新读取校验头及数据crc32
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload blocks error:", err)
+	}
+
+	var buf = make([]byte, blocks[0].Len())
+	if _, err = blocks[0].Read(buf); err != nil || string(buf) != "Hello Gopher" {
+		t.Fatal("WriteAt error:", err, string(buf))
+	}
+}
+
+func TestBlock_Compression(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		block = &blocks[0]
+		data  = bytes.Repeat([]byte("compressed block data "), 100)
+	)
+
+	if err = block.SetCompression(CompressGzip); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload blocks error:", err)
+	}
+
+	block = &blocks[0]
+	if block.Compression() != CompressGzip || block.Len() != uint32(len(data)) || block.StoredLen() >= block.Len() {
+		t.Fatal("compressed block header error:", block)
+	}
+
+	result, err := io.ReadAll(block.NewReader())
+	if err != nil || !bytes.Equal(result, data) {
+		t.Fatal("compressed block read error:", err)
+	}
+
+	if _, err = block.WriteAt([]byte("COMPRESSED"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = make([]byte, 16)
+	if _, err = block.ReadAt(buf, 0); err != nil || string(buf) != "COMPRESSED block" {
+		t.Fatal("compressed block WriteAt error:", err, string(buf))
+	}
+
+	// 流式写入
+	var writer = block.NewWriter()
+	if _, err = writer.Write(data[:100]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Len() != 100 {
+		t.Fatal("compressed writer length error:", block.Len())
+	}
+}
+
+func TestBlock_Encryption(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		block = &blocks[0]
+		data  = []byte("secret credentials")
+		key   = KeyFunc(func() ([]byte, error) { return bytes.Repeat([]byte{1}, 16), nil })
+		wrong = KeyFunc(func() ([]byte, error) { return bytes.Repeat([]byte{2}, 16), nil })
+	)
+
+	if err = block.SetEncryption(EncryptAESGCM); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.Write(data); !errors.Is(err, ErrNoKey) {
+		t.Fatal("write without key error:", err)
+	}
+
+	block.SetKey(key)
+	if err = block.SetCompression(CompressDeflate); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload blocks error:", err)
+	}
+
+	block = &blocks[0]
+	if block.Encryption() != EncryptAESGCM || block.Len() != uint32(len(data)) {
+		t.Fatal("encrypted block header error:", block)
+	}
+
+	var buf = make([]byte, block.Len())
+	if _, err = block.Read(buf); !errors.Is(err, ErrNoKey) {
+		t.Fatal("read without key error:", err)
+	}
+
+	block.SetKey(wrong)
+	if _, err = block.Read(buf); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("read with wrong key error:", err)
+	}
+
+	block.SetKey(key)
+	if _, err = block.Read(buf); err != nil || !bytes.Equal(buf, data) {
+		t.Fatal("read with key error:", err, string(buf))
+	}
+
+	// 存储的数据不包含明文
+	var stored = make([]byte, block.StoredLen())
+	if _, err = block.readAt(stored, 0); err != nil || bytes.Contains(stored, data) {
+		t.Fatal("stored data error:", err)
+	}
+
+	// 篡改头中的压缩方式并重新计算头crc32
+	var h = block.header
+	h.Flags &^= flagCompress
+	if err = block.writeHeader(&h); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload tampered blocks error:", err)
+	}
+
+	blocks[0].SetKey(key)
+	if _, err = blocks[0].Read(buf); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("read tampered header error:", err)
+	}
+
+	var writer = block.NewWriter()
+	if _, err = writer.Write([]byte("streamed secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := io.ReadAll(block.NewReader())
+	if err != nil || string(result) != "streamed secret" {
+		t.Fatal("encrypted writer error:", err, string(result))
+	}
+}
+
+func TestBlock_Signature(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		block = &blocks[0]
+		data  = []byte(`{"license":"pro","expire":"2030-01-01"}`)
+	)
+
+	block.SetSigner(priv)
+	if _, err = block.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload blocks error:", err)
+	}
+
+	block = &blocks[0]
+	if !block.Signed() || block.Len() != uint32(len(data)) {
+		t.Fatal("signed block header error:", block)
+	}
+
+	if err = block.Verify(pub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.Verify(other); !errors.Is(err, ErrSignature) {
+		t.Fatal("verify with other key error:", err)
+	}
+
+	block.SetVerifier(pub)
+	result, err := io.ReadAll(block.NewReader())
+	if err != nil || !bytes.Equal(result, data) {
+		t.Fatal("signed block read error:", err, string(result))
+	}
+
+	// 篡改头中的原始大小或数据标志位并重新计算头crc32
+	for _, tamper := range []func(h *Header){
+		func(h *Header) { h.RawLen -= 10 },
+		func(h *Header) { h.Flags |= uint32(CompressDeflate) },
+	} {
+		var h = block.header
+		tamper(&h)
+		if err = block.writeHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+
+		if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+			t.Fatal("reload tampered blocks error:", err)
+		}
+
+		var tampered = &blocks[0]
+		if err = tampered.Verify(pub); !errors.Is(err, ErrSignature) {
+			t.Fatal("verify tampered header error:", err)
+		}
+
+		tampered.SetVerifier(pub)
+		if _, err = io.ReadAll(tampered.NewReader()); !errors.Is(err, ErrSignature) {
+			t.Fatal("read tampered header error:", err)
+		}
+	}
+
+	// 篡改数据（客户重新导入未签名数据）
+	block.SetSigner(nil)
+	if _, err = block.Write([]byte(`{"license":"enterprise","expire":"2099-01-01"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = make([]byte, block.Len())
+	if _, err = block.Read(buf); !errors.Is(err, ErrUnsigned) {
+		t.Fatal("read unsigned block error:", err)
+	}
+
+	// 流式写入签名
+	block.SetSigner(priv)
+
+	var writer = block.NewWriter()
+	if _, err = writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.Verify(pub); err != nil {
+		t.Fatal("signed writer error:", err)
+	}
+}
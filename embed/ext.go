@@ -0,0 +1,288 @@
+package embed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// Extension types of the header v2 extension area, other types are free
+// for applications.
+const (
+	ExtName  uint8 = 1 // 块名称，块之后没有名称标签时使用
+	ExtType  uint8 = 2 // 数据的MIME类型
+	ExtNonce uint8 = 3 // 加密数据的nonce，由写入维护
+)
+
+// DefaultExtSize is the size of the extension area reserved by Upgrade.
+const DefaultExtSize = 64
+
+// 扩展区格式：crc32(4) + 记录，记录为类型(1) + 长度(2) + 值，类型0结束
+const (
+	extHeaderSize = 4
+	maxExtSize    = flagExtSize >> 16 * extUnit
+)
+
+// checkVersion 校验头格式版本及扩展区大小
+func checkVersion(h header) error {
+	if h.version() > headerVersion {
+		return fmt.Errorf("unsupported header version: %d", h.version())
+	}
+
+	if h.extSize() > 0 && (h.version() < 2 || h.extSize() >= h.DataCap) {
+		return errors.New("invalid extension size")
+	}
+
+	return nil
+}
+
+// encodeExtensions 编码扩展区，不足的部分以0填充
+func encodeExtensions(size uint32, extensions map[uint8][]byte) (data []byte, err error) {
+	data = make([]byte, extHeaderSize, size)
+
+	var types = make([]uint8, 0, len(extensions))
+	for typ := range extensions {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+
+	for _, typ := range types {
+		var value = extensions[typ]
+		if typ == 0 || len(value) > 0xffff {
+			return nil, fmt.Errorf("invalid extension %d", typ)
+		}
+
+		data = append(data, typ)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(value)))
+		data = append(data, value...)
+	}
+
+	if uint32(len(data)) > size {
+		return nil, fmt.Errorf("extensions too large: %d > %d", len(data), size)
+	}
+
+	data = data[:size]
+	binary.BigEndian.PutUint32(data, crc32.ChecksumIEEE(data[extHeaderSize:]))
+
+	return
+}
+
+// decodeExtensions 解析扩展区
+func decodeExtensions(data []byte) (extensions map[uint8][]byte, err error) {
+	if len(data) < extHeaderSize || binary.BigEndian.Uint32(data) != crc32.ChecksumIEEE(data[extHeaderSize:]) {
+		return nil, errors.New("invalid extension checksum")
+	}
+
+	extensions = make(map[uint8][]byte)
+	for data = data[extHeaderSize:]; len(data) > 0 && data[0] != 0; {
+		if len(data) < 3 {
+			return nil, errors.New("invalid extension area")
+		}
+
+		var typ, size = data[0], int(binary.BigEndian.Uint16(data[1:]))
+		if len(data) < 3+size {
+			return nil, errors.New("invalid extension area")
+		}
+
+		extensions[typ], data = data[3:3+size], data[3+size:]
+	}
+
+	return
+}
+
+// readExtensions 读取扩展区，v1的头没有扩展区
+func readExtensions(file io.ReaderAt, h Header) (extensions map[uint8][]byte, err error) {
+	if h.extSize() == 0 {
+		return
+	}
+
+	var data = make([]byte, h.extSize())
+	if _, err = file.ReadAt(data, h.Offset+int64(h.capacity())); err != nil {
+		return
+	}
+
+	return decodeExtensions(data)
+}
+
+// blockName 块之后的名称标签，没有时使用扩展区中的名称
+func blockName(file storage, h Header) (name string, err error) {
+	if name, err = getName(file, h); err != nil || name != "" {
+		return
+	}
+
+	// 扩展区损坏不影响读取块
+	extensions, _ := readExtensions(file, h)
+	if name = string(extensions[ExtName]); checkName(name) != nil {
+		name = ""
+	}
+
+	return
+}
+
+// Version returns the header format version of the block, 1 or 2.
+func (b *Block) Version() int {
+	defer b.rlock()()
+
+	return b.header.version()
+}
+
+// Extension returns the value of the extension typ, nil if it is not set.
+func (b *Block) Extension(typ uint8) (value []byte, err error) {
+	defer b.rlock()()
+
+	extensions, err := readExtensions(b.file, b.header)
+	if err != nil {
+		return
+	}
+
+	return extensions[typ], nil
+}
+
+// SetExtension sets the extension typ in the extension area of a version 2
+// block, an empty value removes it. The extension area has the size given
+// to Upgrade, the data is not moved.
+func (b *Block) SetExtension(typ uint8, value []byte) (err error) {
+	defer b.wlock()()
+
+	if b.header.extSize() == 0 {
+		return errors.New("block has no extension area, see Upgrade")
+	}
+
+	if typ == ExtName && len(value) > 0 {
+		if err = checkName(string(value)); err != nil {
+			return
+		}
+	}
+
+	extensions, err := readExtensions(b.file, b.header)
+	if err != nil {
+		// 扩展区损坏时重建
+		extensions = make(map[uint8][]byte)
+	}
+
+	if len(value) == 0 {
+		delete(extensions, typ)
+	} else {
+		extensions[typ] = value
+	}
+
+	data, err := encodeExtensions(b.header.extSize(), extensions)
+	if err != nil {
+		return
+	}
+
+	if err = b.writeExtensions(data); err != nil {
+		return
+	}
+
+	if err = b.file.Sync(); err != nil {
+		return
+	}
+
+	// 名称标签优先
+	if tag, _ := getName(b.file, b.header); typ == ExtName && tag == "" {
+		b.name = string(value)
+	}
+
+	return
+}
+
+// Upgrade rewrites the header of a version 1 block as version 2, reserving
+// an extension area of size bytes, rounded up to 16, at the end of the
+// capacity. The data is kept in place, so the block must have that much
+// free capacity. Atomic and chained blocks are not upgraded.
+func (b *Block) Upgrade(size uint32) (err error) {
+	defer b.wlock()()
+
+	if b.header.version() >= 2 {
+		return fmt.Errorf("block is already version %d", b.header.version())
+	}
+
+	if b.header.slotted() || b.chained() {
+		return errors.New("atomic or chained blocks can not be upgraded")
+	}
+
+	if size = (size + extUnit - 1) / extUnit * extUnit; size < extUnit || size > maxExtSize {
+		return fmt.Errorf("invalid extension size: %d", size)
+	}
+
+	// 扩展区占用容量末尾的空闲空间
+	if size >= b.header.DataCap || b.header.DataLen > b.header.DataCap-size {
+		return fmt.Errorf("not enough free capacity: %d bytes needed", size)
+	}
+
+	var h = b.header
+	h.Flags = h.Flags&^(flagExtSize|flagVersion) | size/extUnit<<16 | headerVersion<<24
+
+	// 先写扩展区，头写入后生效
+	data, err := encodeExtensions(size, nil)
+	if err != nil {
+		return
+	}
+
+	if _, err = b.file.WriteAt(data, h.Offset+int64(h.capacity())); err != nil {
+		return
+	}
+
+	if err = b.file.Sync(); err != nil {
+		return
+	}
+
+	if err = b.writeHeader(&h); err != nil {
+		return
+	}
+
+	b.header = h
+
+	return
+}
+
+// headerNonce 存放在扩展区中的加密数据的nonce，没有时为nil，A/B槽的nonce随各槽
+// 的数据存放
+func (b Block) headerNonce() []byte {
+	if b.header.slotted() || b.header.extSize() == 0 || b.header.encryption() == EncryptNone {
+		return nil
+	}
+
+	// 扩展区损坏时解密失败
+	extensions, _ := readExtensions(b.file, b.header)
+
+	return extensions[ExtNonce]
+}
+
+// splitNonce 非原子写入有扩展区的块时，将加密数据开头的nonce移入扩展区，返回编码
+// 后的扩展区及需存储的数据，ext为nil时不修改扩展区。扩展区空间不足时nonce保留在
+// 数据之前，并删除扩展区中旧的nonce
+func (b *Block) splitNonce(e Encryption, data []byte) (ext, stored []byte, err error) {
+	var n = e.nonceSize()
+	if n == 0 || b.atomic || b.header.extSize() == 0 || len(data) < n {
+		return nil, data, nil
+	}
+
+	extensions, err := readExtensions(b.file, b.header)
+	if err != nil {
+		// 扩展区损坏时重建
+		extensions = make(map[uint8][]byte)
+	}
+
+	extensions[ExtNonce] = data[:n]
+	if ext, err = encodeExtensions(b.header.extSize(), extensions); err == nil {
+		return ext, data[n:], nil
+	}
+
+	delete(extensions, ExtNonce)
+	if ext, err = encodeExtensions(b.header.extSize(), extensions); err != nil {
+		return
+	}
+
+	return ext, data, nil
+}
+
+// writeExtensions 写入编码后的扩展区
+func (b *Block) writeExtensions(data []byte) (err error) {
+	_, err = b.file.WriteAt(data, b.header.Offset+int64(b.header.capacity()))
+	return
+}
@@ -0,0 +1,229 @@
+package embed
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBlock_Upgrade(t *testing.T) {
+	var emd = openTestFile(t, Size1KB, Size1KB)
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 2 {
+		t.Fatal("blocks error:", err, len(blocks))
+	}
+
+	var block = &blocks[0]
+	if _, err = block.Write([]byte("v1 data")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.SetExtension(ExtType, []byte("text/plain")); err == nil {
+		t.Fatal("set extension of v1 block should fail")
+	}
+
+	// 容量不足
+	if _, err = blocks[1].Write(bytes.Repeat([]byte("x"), 1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = blocks[1].Upgrade(DefaultExtSize); err == nil {
+		t.Fatal("upgrade full block should fail")
+	}
+
+	if err = block.Upgrade(50); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Version() != 2 || block.Cap() != 1024-64 {
+		t.Fatal("upgraded block error:", block.Version(), block.Cap())
+	}
+
+	if err = block.Upgrade(DefaultExtSize); err == nil {
+		t.Fatal("upgrade v2 block should fail")
+	}
+
+	if err = block.SetExtension(ExtName, []byte("config")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.SetExtension(ExtType, []byte("text/plain")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.SetExtension(9, []byte(strings.Repeat("x", 64))); err == nil {
+		t.Fatal("extension too large should fail")
+	}
+
+	// 重新读取，数据保持不变
+	found, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := io.ReadAll(found.NewReader()); err != nil || string(data) != "v1 data" {
+		t.Fatal("upgraded data error:", err, string(data))
+	}
+
+	if value, err := found.Extension(ExtType); err != nil || string(value) != "text/plain" {
+		t.Fatal("extension error:", err, string(value))
+	}
+
+	if _, err = found.Write(bytes.Repeat([]byte("y"), 1000)); err == nil {
+		t.Fatal("write into extension area should fail")
+	}
+
+	// 写入及原子写入保留扩展区
+	found.SetAtomic(true)
+	if _, err = found.Write([]byte("atomic")); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err = emd.Lookup("config"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := io.ReadAll(found.NewReader()); err != nil || string(data) != "atomic" || found.Version() != 2 {
+		t.Fatal("atomic data error:", err, string(data), found.Version())
+	}
+}
+
+func TestBlock_HeaderNonce(t *testing.T) {
+	var emd = openTestFile(t, Size1KB, Size1KB, Size1KB)
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 3 {
+		t.Fatal("blocks error:", err, len(blocks))
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		block = &blocks[0]
+		data  = []byte("secret credentials")
+		key   = KeyFunc(func() ([]byte, error) { return bytes.Repeat([]byte{1}, 16), nil })
+	)
+	if err = block.Upgrade(DefaultExtSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.SetEncryption(EncryptAESGCM); err != nil {
+		t.Fatal(err)
+	}
+	block.SetKey(key)
+	block.SetSigner(priv)
+
+	// v2的块nonce存放在扩展区，不占用数据的容量
+	if _, err = block.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if nonce, err := block.Extension(ExtNonce); err != nil || len(nonce) != 12 {
+		t.Fatal("nonce extension error:", err, len(nonce))
+	}
+
+	if block.StoredLen() != uint32(len(data)+16+ed25519.SignatureSize) || block.Verify(pub) != nil {
+		t.Fatal("stored data error:", block.StoredLen(), block.Verify(pub))
+	}
+
+	if result, err := io.ReadAll(block.NewReader()); err != nil || !bytes.Equal(result, data) {
+		t.Fatal("read error:", err, string(result))
+	}
+
+	var writer = block.NewWriter()
+	if _, err = writer.Write([]byte("streamed secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.StoredLen() != uint32(len("streamed secret")+16+ed25519.SignatureSize) || block.Verify(pub) != nil {
+		t.Fatal("streamed data error:", block.StoredLen(), block.Verify(pub))
+	}
+
+	// 移植到v1的块时nonce存放在数据之前，签名仍然有效
+	if err = blocks[1].transplant(*block); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks[1].SetKey(key)
+	if blocks[1].StoredLen() != block.StoredLen()+12 || blocks[1].Verify(pub) != nil {
+		t.Fatal("transplanted data error:", blocks[1].StoredLen(), blocks[1].Verify(pub))
+	}
+
+	if result, err := io.ReadAll(blocks[1].NewReader()); err != nil || string(result) != "streamed secret" {
+		t.Fatal("transplanted read error:", err, string(result))
+	}
+
+	// 原子写入的nonce随槽的数据存放
+	block.SetAtomic(true)
+	if _, err = block.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.StoredLen() != uint32(len(data)+28+ed25519.SignatureSize) || block.Verify(pub) != nil {
+		t.Fatal("atomic data error:", block.StoredLen(), block.Verify(pub))
+	}
+
+	// 扩展区放不下nonce时存放在数据之前
+	var small = &blocks[2]
+	if err = small.Upgrade(extUnit); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = small.SetEncryption(EncryptAESGCM); err != nil {
+		t.Fatal(err)
+	}
+	small.SetKey(key)
+
+	if _, err = small.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if small.StoredLen() != uint32(len(data)+28) {
+		t.Fatal("small extension data error:", small.StoredLen())
+	}
+
+	// 重新读取
+	if blocks, err = emd.Blocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range blocks {
+		blocks[i].SetKey(key)
+		if result, err := io.ReadAll(blocks[i].NewReader()); err != nil || len(result) == 0 {
+			t.Fatal("reload error:", i, err)
+		}
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	var h = emptyHeader
+	h.DataCap = 1024
+
+	if err := checkVersion(h); err != nil {
+		t.Fatal(err)
+	}
+
+	var cases = []uint32{
+		1 << 16,        // v1没有扩展区
+		3 << 24,        // 未知版本
+		2 << 24,        // v2无扩展区
+		2<<24 | 64<<16, // 扩展区超过容量
+	}
+
+	for i, flags := range cases {
+		h.Flags = flags
+		if (checkVersion(h) == nil) != (i == 2) {
+			t.Fatalf("checkVersion(%#x) error", flags)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package embed
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS is a read-only file system over named blocks and appended payloads,
+// or over a zip or tar archive. It implements fs.ReadDirFS and fs.StatFS,
+// so it can be passed to http.FileServerFS or template.ParseFS.
+type FS struct {
+	nodes map[string]*node
+}
+
+// node 文件或目录
+type node struct {
+	name    string // 完整路径
+	size    int64
+	modTime time.Time
+	dir     bool
+	open    func() (*io.SectionReader, error) // 打开文件
+	entries []string                          // 目录下的名称，已排序
+}
+
+func (n *node) Name() string {
+	return path.Base(n.name)
+}
+
+func (n *node) Size() int64 {
+	return n.size
+}
+
+func (n *node) Mode() fs.FileMode {
+	if n.dir {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+func (n *node) ModTime() time.Time {
+	return n.modTime
+}
+
+func (n *node) IsDir() bool {
+	return n.dir
+}
+
+func (n *node) Sys() any {
+	return nil
+}
+
+func newFS() *FS {
+	return &FS{nodes: map[string]*node{
+		".": {name: ".", dir: true},
+	}}
+}
+
+// add 添加文件或目录，自动创建上级目录，已存在时忽略
+func (f *FS) add(n *node) {
+	if !fs.ValidPath(n.name) || f.nodes[n.name] != nil {
+		return
+	}
+
+	var dir = path.Dir(n.name)
+	if f.nodes[dir] == nil {
+		f.add(&node{name: dir, dir: true, modTime: n.modTime})
+	}
+
+	var parent = f.nodes[dir]
+	if !parent.dir {
+		return
+	}
+
+	var base = path.Base(n.name)
+	if index, found := slices.BinarySearch(parent.entries, base); !found {
+		parent.entries = slices.Insert(parent.entries, index, base)
+	}
+
+	f.nodes[n.name] = n
+}
+
+func (f *FS) lookup(op, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	n, ok := f.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return n, nil
+}
+
+// Open implements fs.FS.
+func (f *FS) Open(name string) (fs.File, error) {
+	n, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.dir {
+		return &dirFile{fs: f, node: n}, nil
+	}
+
+	reader, err := n.open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &regularFile{node: n, SectionReader: reader}, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.lookup("stat", name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *FS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	n, err := f.lookup("readdir", name)
+	if err != nil {
+		return
+	}
+
+	if !n.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	return f.entries(n), nil
+}
+
+func (f *FS) entries(n *node) []fs.DirEntry {
+	var entries = make([]fs.DirEntry, 0, len(n.entries))
+	for _, base := range n.entries {
+		entries = append(entries, fs.FileInfoToDirEntry(f.nodes[path.Join(n.name, base)]))
+	}
+
+	return entries
+}
+
+// regularFile 打开的文件
+type regularFile struct {
+	*io.SectionReader
+	node *node
+}
+
+func (f *regularFile) Stat() (fs.FileInfo, error) {
+	return f.node, nil
+}
+
+func (f *regularFile) Close() error {
+	return nil
+}
+
+// dirFile 打开的目录
+type dirFile struct {
+	fs     *FS
+	node   *node
+	offset int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) {
+	return d.node, nil
+}
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) Close() error {
+	return nil
+}
+
+func (d *dirFile) ReadDir(count int) (entries []fs.DirEntry, err error) {
+	var all = d.fs.entries(d.node)
+	if entries = all[d.offset:]; count > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+
+		entries = entries[:min(count, len(entries))]
+	}
+
+	d.offset += len(entries)
+
+	return
+}
+
+// FS returns a file system of the named blocks and the payloads, a block is
+// a file named by its name with the mod time of its last write. Blocks take
+// precedence over payloads with the same name. The blocks are read when the
+// files are opened, the set of files is fixed when FS is called.
+func (e *Embed) FS() (_ *FS, err error) {
+	blocks, err := e.Blocks()
+	if err != nil {
+		return
+	}
+
+	payloads, err := e.Payloads()
+	if err != nil {
+		return
+	}
+
+	var f = newFS()
+	for _, b := range blocks {
+		if b.name == "" {
+			continue
+		}
+
+		f.add(&node{
+			name:    b.name,
+			size:    int64(b.Len()),
+			modTime: b.ModTime(),
+			open: func() (*io.SectionReader, error) {
+				return b.NewReader(), nil
+			},
+		})
+	}
+
+	for _, p := range payloads {
+		f.add(&node{
+			name:    p.name,
+			size:    p.size,
+			modTime: p.modTime,
+			open: func() (*io.SectionReader, error) {
+				return p.NewReader(), nil
+			},
+		})
+	}
+
+	return f, nil
+}
+
+// Files returns a file system of the named blocks and the payloads of the
+// running executable, see Embed.FS.
+func Files() (_ *FS, err error) {
+	emd, err := self()
+	if err != nil {
+		return
+	}
+
+	return emd.FS()
+}
+
+// Archive returns a file system of the zip, tar or gzip compressed tar
+// archive stored in the block.
+func (b *Block) Archive() (*FS, error) {
+	var reader = b.NewReader()
+	return OpenArchive(reader, reader.Size())
+}
+
+// Archive returns a file system of the zip, tar or gzip compressed tar
+// archive appended as the payload.
+func (p Payload) Archive() (*FS, error) {
+	return OpenArchive(p.NewReader(), p.size)
+}
+
+// OpenArchive returns a file system of a zip, tar or gzip compressed tar
+// archive, the format is detected from the content.
+func OpenArchive(r io.ReaderAt, size int64) (_ *FS, err error) {
+	var magic = make([]byte, 512)
+
+	n, err := r.ReadAt(magic, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return openZip(r, size)
+	case bytes.HasPrefix(magic, []byte("\x1f\x8b")):
+		// 解压到内存
+		reader, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		return openTar(bytes.NewReader(data), int64(len(data)))
+	case len(magic) >= 262 && string(magic[257:262]) == "ustar":
+		return openTar(r, size)
+	}
+
+	return nil, errors.New("unknown archive format")
+}
+
+func openZip(r io.ReaderAt, size int64) (_ *FS, err error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return
+	}
+
+	var f = newFS()
+	for _, file := range reader.File {
+		var name = strings.TrimSuffix(file.Name, "/")
+		if strings.HasSuffix(file.Name, "/") {
+			f.add(&node{name: name, dir: true, modTime: file.Modified})
+			continue
+		}
+
+		// 未压缩的文件直接读取，否则打开时解压到内存
+		var open = func() (*io.SectionReader, error) {
+			reader, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, err
+			}
+
+			return io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))), nil
+		}
+
+		if offset, err := file.DataOffset(); err == nil && file.Method == zip.Store {
+			open = func() (*io.SectionReader, error) {
+				return io.NewSectionReader(r, offset, int64(file.UncompressedSize64)), nil
+			}
+		}
+
+		f.add(&node{name: name, size: int64(file.UncompressedSize64), modTime: file.Modified, open: open})
+	}
+
+	return f, nil
+}
+
+// countReader 统计已读取的长度
+type countReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countReader) Read(buf []byte) (n int, err error) {
+	n, err = c.reader.Read(buf)
+	c.count += int64(n)
+
+	return
+}
+
+func openTar(r io.ReaderAt, size int64) (_ *FS, err error) {
+	var (
+		counter = &countReader{reader: io.NewSectionReader(r, 0, size)}
+		reader  = tar.NewReader(counter)
+		f       = newFS()
+	)
+
+	for {
+		var header *tar.Header
+		if header, err = reader.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return f, nil
+			}
+
+			return
+		}
+
+		var name = strings.TrimSuffix(path.Clean(header.Name), "/")
+		switch header.Typeflag {
+		case tar.TypeDir:
+			f.add(&node{name: name, dir: true, modTime: header.ModTime})
+		case tar.TypeReg:
+			// 普通文件的数据紧跟在头之后，稀疏文件不支持
+			if _, sparse := header.PAXRecords["GNU.sparse.major"]; sparse {
+				continue
+			}
+
+			var offset, length = counter.count, header.Size
+			f.add(&node{
+				name:    name,
+				size:    length,
+				modTime: header.ModTime,
+				open: func() (*io.SectionReader, error) {
+					return io.NewSectionReader(r, offset, length), nil
+				},
+			})
+		}
+	}
+}
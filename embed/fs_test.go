@@ -0,0 +1,124 @@
+package embed
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEmbed_FS(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"index.html\x00", Size1KB+NameTag+"config\x00", Size1KB+"3")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = blocks[0].Write([]byte("<html></html>")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = emd.AddPayload("static/app.js", strings.NewReader("console.log(1)"), time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := emd.FS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = fstest.TestFS(files, "index.html", "config", "static/app.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := files.Stat("index.html")
+	if err != nil || info.Size() != 13 || info.ModTime().Unix() != blocks[0].header.UpdateTime {
+		t.Fatal("stat error:", err, info)
+	}
+
+	if data, err := fs.ReadFile(files, "static/app.js"); err != nil || string(data) != "console.log(1)" {
+		t.Fatal("read payload error:", err, string(data))
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	var modTime = time.Unix(1700000000, 0)
+
+	// zip：压缩及未压缩的文件
+	var zipData bytes.Buffer
+	var zw = zip.NewWriter(&zipData)
+	for i, name := range []string{"a.txt", "dir/b.txt"} {
+		var method = zip.Deflate
+		if i == 0 {
+			method = zip.Store
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: modTime})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = w.Write([]byte("content of " + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// tar
+	var tarData bytes.Buffer
+	var tw = tar.NewWriter(&tarData)
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		var content = "content of " + name
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: modTime, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.WriteString(tw, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var emd = openTestFile(t, Size4KB+"1")
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{zipData.Bytes(), tarData.Bytes()} {
+		// 压缩存储的块同样可用
+		_ = blocks[0].SetCompression(CompressGzip)
+		if _, err = blocks[0].Write(data); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := blocks[0].Archive()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = fstest.TestFS(files, "a.txt", "dir/b.txt"); err != nil {
+			t.Fatal(err)
+		}
+
+		if content, err := fs.ReadFile(files, "dir/b.txt"); err != nil || string(content) != "content of dir/b.txt" {
+			t.Fatal("read archive error:", err, string(content))
+		}
+	}
+
+	if _, err = OpenArchive(strings.NewReader("plain text"), 10); err == nil {
+		t.Fatal("open unknown archive should fail")
+	}
+}
@@ -0,0 +1,213 @@
+package embed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Fix is a repair available for a finding of Fsck.
+type Fix uint8
+
+const (
+	FixNone      Fix = iota // 无法修复
+	FixHeaderCRC            // 头仅crc32错误，重新计算头crc32
+	FixZero                 // 头完整但数据损坏，清空块
+)
+
+var fixes = map[Fix]string{
+	FixNone:      "none",
+	FixHeaderCRC: "header-crc",
+	FixZero:      "zero",
+}
+
+func (f Fix) String() string {
+	if name, exists := fixes[f]; exists {
+		return name
+	}
+
+	return fmt.Sprintf("fix(%d)", f)
+}
+
+var errBrokenChain = errors.New("broken chain")
+
+// ErrNotHeader reports a magic occurrence that is not a block header, such
+// as the magic in the program text.
+var ErrNotHeader = errors.New("not a block header")
+
+// ErrDamagedHeader reports a header which is rejected as a whole but still
+// reserves a capacity within the file, or is recorded in the index, so a
+// block has been lost.
+var ErrDamagedHeader = errors.New("damaged block header")
+
+// Finding is a magic occurrence found by Fsck.
+type Finding struct {
+	Header        // 解析的头，A/B槽为恢复后的头
+	Name   string // 块名称
+	Err    error  // 拒绝的原因，nil表示有效
+	Fix    Fix    // 可用的修复
+}
+
+// HeaderOffset returns the offset of the magic, Offset is the offset of the
+// data following the header.
+func (f Finding) HeaderOffset() int64 {
+	return f.Offset - headerSize
+}
+
+func (f Finding) String() string {
+	var status = "ok"
+	if f.Err != nil {
+		status = f.Err.Error()
+	}
+
+	return fmt.Sprintf("%#x\t%d\t%s\t%s\t%s", f.HeaderOffset(), f.DataCap, f.Name, status, f.Fix)
+}
+
+// Fsck scans the whole file, ignoring the index, and reports every magic
+// occurrence with the reason it is rejected by Blocks. Occurrences that
+// are not headers are reported with ErrNotHeader. Damaged headers, which
+// keep a capacity within the file or are at an offset of the index, are
+// reported with ErrDamagedHeader, as are index entries whose magic is gone.
+func (e *Embed) Fsck() (findings []Finding, err error) {
+	content, headers, err := e.scanHeaders()
+	if err != nil {
+		return
+	}
+
+	// 索引中记录的头
+	var (
+		_, entries = loadIndex(e.file)
+		indexed    = make(map[int64]indexEntry, len(entries))
+	)
+	for _, entry := range entries {
+		indexed[entry.Offset+headerSize] = entry
+	}
+
+	var valid []Header
+	for _, h := range headers {
+		var f = Finding{Header: h}
+		if f.Header, f.Err = checkHeader(e.file, h); f.Err == nil {
+			valid = append(valid, f.Header)
+		} else if !plausible(content, h) {
+			f.Err = ErrNotHeader
+			if _, exists := indexed[h.Offset]; exists || damaged(content, h) {
+				f.Err = ErrDamagedHeader
+			}
+		} else {
+			f.Fix = fixOf(e.file, content, h)
+		}
+
+		if f.Name, err = blockName(e.file, f.Header); err != nil {
+			return
+		}
+
+		findings = append(findings, f)
+		delete(indexed, h.Offset)
+	}
+
+	// 索引中的头已找不到magic
+	for _, entry := range entries {
+		if _, exists := indexed[entry.Offset+headerSize]; exists {
+			findings = append(findings, Finding{
+				Header: Header{header: header{DataCap: entry.DataCap}, Offset: entry.Offset + headerSize},
+				Name:   entry.Name,
+				Err:    ErrDamagedHeader,
+			})
+		}
+	}
+
+	// 链头有效但链断开
+	var heads, _ = linkChains(valid)
+	for i, f := range findings {
+		if f.Err != nil || f.chained() || f.NextOffset == 0 {
+			continue
+		}
+
+		if !containsHeader(heads, f.Offset) {
+			findings[i].Err = errBrokenChain
+		}
+	}
+
+	return
+}
+
+func containsHeader(headers []Header, offset int64) bool {
+	for _, h := range headers {
+		if h.Offset == offset {
+			return true
+		}
+	}
+
+	return false
+}
+
+// plausible 头crc32错误时，容量、长度及标志位合法才视为损坏的头
+func plausible(content int64, h Header) bool {
+	if h.verifyHeader() == nil {
+		return true
+	}
+
+	return h.DataCap > 0 && h.Offset+int64(h.DataCap) <= content && h.DataLen <= h.capacity() && h.Flags&^knownFlags == 0 && checkVersion(h.header) == nil
+}
+
+// damaged 头不合法但容量仍在文件内，视为损坏的头，程序中的magic字符串之后
+// 一般为其他字符串，容量超出文件
+func damaged(content int64, h Header) bool {
+	return h.DataCap > 0 && h.Offset+int64(h.DataCap) <= content
+}
+
+// fixOf 判断被拒绝的头可用的修复
+func fixOf(file io.ReaderAt, content int64, h Header) Fix {
+	if h.DataCap == 0 || h.Offset+int64(h.DataCap) > content {
+		return FixNone
+	}
+
+	// 头完整，数据损坏
+	if h.verifyHeader() == nil {
+		return FixZero
+	}
+
+	// 仅头crc32错误：数据crc32一致
+	if h.slotted() {
+		return FixNone
+	}
+
+	var buf = make([]byte, h.DataLen)
+	if _, err := file.ReadAt(buf, h.Offset); err != nil {
+		return FixNone
+	}
+
+	var fixed = h
+	if sum, err := fixed.checksum(); err == nil {
+		fixed.CRC32 = sum
+	}
+
+	if fixed.Verify(buf) == nil {
+		return FixHeaderCRC
+	}
+
+	return FixNone
+}
+
+// Repair applies the fix of the finding: FixHeaderCRC rewrites the header
+// checksum, FixZero empties the block by resetting its header and filling
+// its data with the filler of the Size constants.
+func (e *Embed) Repair(f Finding) (err error) {
+	switch f.Fix {
+	case FixHeaderCRC:
+		var h = f.Header
+		if err = (&Block{file: e.file}).writeHeader(&h); err != nil {
+			return
+		}
+	case FixZero:
+		if err = resetHeader(e.file, f.Header); err != nil {
+			return
+		}
+	default:
+		return fmt.Errorf("block at %#x can not be repaired", f.HeaderOffset())
+	}
+
+	e.index = nil
+
+	return
+}
@@ -0,0 +1,130 @@
+package embed
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEmbed_Fsck(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1", Size1KB+"2", Size1KB+"3", Size(magic+"\xff\xff\xff\xff stray magic"+strings.Repeat(" ", headerSize)))
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 3 {
+		t.Fatal("blocks error:", err, len(blocks))
+	}
+
+	for i := range blocks {
+		if _, err = blocks[i].Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 块1头crc32损坏，块2数据损坏
+	var file = blocks[0].file
+	if _, err = file.WriteAt([]byte{0, 0, 0, 0}, blocks[1].header.Offset-headerSize+8); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = file.WriteAt([]byte("D"), blocks[2].header.Offset); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := emd.Fsck()
+	if err != nil || len(findings) != 4 {
+		t.Fatal("fsck error:", err, findings)
+	}
+
+	var expected = []Fix{FixNone, FixHeaderCRC, FixZero, FixNone}
+	for i, f := range findings {
+		if f.Fix != expected[i] || (f.Err == nil) != (i == 0) {
+			t.Fatal("finding error:", i, f)
+		}
+	}
+
+	if !errors.Is(findings[3].Err, ErrNotHeader) {
+		t.Fatal("stray magic error:", findings[3].Err)
+	}
+
+	if err = emd.Repair(findings[0]); err == nil {
+		t.Fatal("repair valid block should fail")
+	}
+
+	for _, f := range findings[1:3] {
+		if err = emd.Repair(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 3 {
+		t.Fatal("blocks after repair error:", err, len(blocks))
+	}
+
+	if readString(t, blocks[1]) != "data" || blocks[2].Len() != 0 {
+		t.Fatal("repair error:", blocks[1], blocks[2])
+	}
+
+	// 清空后的块仍由Size匹配
+	if ok, err := matchSize(file, blocks[2].header, Size1KB+"3"); err != nil || !ok {
+		t.Fatal("match zeroed block error:", err)
+	}
+}
+
+func TestEmbed_FsckIndexed(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1", Size1KB+"2", Size(magic+"\xff\xff\xff\xff stray magic"+strings.Repeat(" ", headerSize)))
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 2 {
+		t.Fatal("blocks error:", err, len(blocks))
+	}
+
+	if err = emd.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 块0除容量外的字段损坏，块1的magic被覆盖
+	var file = blocks[0].file
+	if _, err = file.WriteAt(bytes.Repeat([]byte{0xff}, 8), blocks[0].header.Offset-headerSize+8); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = file.WriteAt(bytes.Repeat([]byte{0xff}, headerSize-20), blocks[0].header.Offset-headerSize+20); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = file.WriteAt(make([]byte, 8), blocks[1].header.Offset-headerSize); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := emd.Fsck()
+	if err != nil || len(findings) != 3 {
+		t.Fatal("fsck error:", err, findings)
+	}
+
+	var damaged = map[int64]bool{}
+	for _, f := range findings {
+		if errors.Is(f.Err, ErrDamagedHeader) {
+			damaged[f.Offset] = true
+		} else if !errors.Is(f.Err, ErrNotHeader) {
+			t.Fatal("finding error:", f)
+		}
+	}
+
+	if !damaged[blocks[0].header.Offset] || !damaged[blocks[1].header.Offset] {
+		t.Fatal("damaged headers not reported:", findings)
+	}
+
+	// 无索引时容量仍在文件内的头视为损坏
+	if err = emd.RemoveIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings, err = emd.Fsck(); err != nil || len(findings) != 2 {
+		t.Fatal("fsck without index error:", err, findings)
+	}
+
+	if !errors.Is(findings[0].Err, ErrDamagedHeader) || !errors.Is(findings[1].Err, ErrNotHeader) {
+		t.Fatal("findings without index error:", findings)
+	}
+}
@@ -0,0 +1,266 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	importPath  = "github.com/zooyer/golib/embed"
+	maxCapacity = 1024 * 1024 * 1024 // 生成的最大容量
+)
+
+var capacityUnits = []struct {
+	name string
+	size uint32
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// ParseCapacity parses a capacity such as 4096, 3KB, 100KB or 48MB, units
+// are powers of 1024.
+func ParseCapacity(s string) (capacity uint32, err error) {
+	var (
+		upper  = strings.ToUpper(strings.TrimSpace(s))
+		number = upper
+		unit   = uint64(1)
+	)
+
+	for _, u := range capacityUnits {
+		if strings.HasSuffix(upper, u.name) {
+			number, unit = strings.TrimSuffix(upper, u.name), uint64(u.size)
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(number, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid capacity %q", s)
+	}
+
+	if n == 0 || n*unit > maxCapacity {
+		return 0, fmt.Errorf("capacity %q out of range", s)
+	}
+
+	return uint32(n * unit), nil
+}
+
+// capacityName 容量名称，使用能整除的最大单位
+func capacityName(capacity uint32) string {
+	for _, u := range capacityUnits {
+		if capacity%u.size == 0 {
+			return fmt.Sprintf("%d%s", capacity/u.size, u.name)
+		}
+	}
+
+	return strconv.Itoa(int(capacity))
+}
+
+// emptyHeaderOf 指定容量的空头
+func emptyHeaderOf(capacity uint32) (data []byte, err error) {
+	var h = emptyHeader
+
+	h.DataCap = capacity
+	if h.CRC32, err = h.checksum(); err != nil {
+		return
+	}
+
+	return h.Encode()
+}
+
+// GenerateSizes writes a Go source file of package pkg declaring a Size
+// constant for every capacity, for example Size3KB for 3KB. The constants
+// are used like the predefined ones:
+//
+//	//go:generate go run github.com/zooyer/golib/embed/cmd gen --output size.go 3KB 48MB
+//	var block = embed.MustMalloc(Size3KB + "1")
+//
+// The generated source is type checked and every constant is verified
+// before it is written. Helper constants are named after the capacities
+// of the file, so files generating different capacities can share a
+// package.
+func GenerateSizes(w io.Writer, pkg string, capacities ...uint32) (err error) {
+	if len(capacities) == 0 {
+		return errors.New("no capacity to generate")
+	}
+
+	capacities = slices.Clone(capacities)
+	slices.Sort(capacities)
+	capacities = slices.Compact(capacities)
+
+	// 辅助常量以本文件的容量命名，同一包中的多个生成文件不冲突
+	var names = make([]string, len(capacities))
+	for i, capacity := range capacities {
+		names[i] = capacityName(capacity)
+	}
+	var prefix = "embed" + strings.Join(names, "")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by embed gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import %q\n\n", importPath)
+
+	// 头中的固定部分
+	fmt.Fprintf(&buf, "const (\n")
+	fmt.Fprintf(&buf, "%sMagic = \"%s\"\n", prefix, toUnicodeEscaped(magic))
+	fmt.Fprintf(&buf, "%sDataLen = \"%s\"\n", prefix, toHexEscaped(emptyDataLen))
+	fmt.Fprintf(&buf, "%sHeaderRest = \"%s\"\n", prefix, toHexEscaped(emptyHeaderRest))
+	fmt.Fprintf(&buf, ")\n\n")
+
+	// 按2的幂逐级翻倍的填充，128字节起使用字面量，避免常量拼接层级过深
+	var width = bits.Len32(capacities[len(capacities)-1])
+	fmt.Fprintf(&buf, "const (\n")
+	for i := 0; i < width; i++ {
+		switch {
+		case i == 0:
+			fmt.Fprintf(&buf, "%sFill1 = \"0\"\n", prefix)
+		case 1<<i == len(size128Byte):
+			fmt.Fprintf(&buf, "%sFill%d = \"%s\"\n", prefix, 1<<i, size128Byte)
+		default:
+			fmt.Fprintf(&buf, "%[1]sFill%[2]d = %[1]sFill%[3]d + %[1]sFill%[3]d\n", prefix, 1<<i, 1<<(i-1))
+		}
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+
+	fmt.Fprintf(&buf, "const (\n")
+	for _, capacity := range capacities {
+		if capacity == 0 || capacity > maxCapacity {
+			return fmt.Errorf("capacity %d out of range", capacity)
+		}
+
+		var data []byte
+		if data, err = emptyHeaderOf(capacity); err != nil {
+			return
+		}
+
+		var fills []string
+		for i := width - 1; i >= 0; i-- {
+			if capacity&(1<<i) != 0 {
+				fills = append(fills, fmt.Sprintf("%sFill%d", prefix, 1<<i))
+			}
+		}
+
+		fmt.Fprintf(&buf, "Size%[2]s embed.Size = %[1]sMagic + \"%[3]s\" + %[1]sDataLen + \"%[4]s\" + %[1]sHeaderRest + %[5]s\n",
+			prefix, capacityName(capacity), toHexEscaped(string(data[8:12])), toHexEscaped(string(data[16:20])), strings.Join(fills, " + "))
+	}
+	fmt.Fprintf(&buf, ")\n")
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return
+	}
+
+	// 编译检查并校验生成的常量
+	if err = checkSizes(capacities, source); err != nil {
+		return fmt.Errorf("generated source is invalid: %w", err)
+	}
+
+	_, err = w.Write(source)
+
+	return
+}
+
+// importerFunc 类型检查时提供embed包的声明
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// checkSizes 将生成的代码作为同一包中的文件类型检查，并校验每个常量的头及填充
+func checkSizes(capacities []uint32, sources ...[]byte) (err error) {
+	var (
+		fset  = token.NewFileSet()
+		files = make([]*ast.File, len(sources))
+	)
+	for i, source := range sources {
+		if files[i], err = parser.ParseFile(fset, fmt.Sprintf("size%d.go", i), source, 0); err != nil {
+			return
+		}
+	}
+
+	var pkg = types.NewPackage(importPath, "embed")
+	pkg.Scope().Insert(types.NewTypeName(token.NoPos, pkg, "Size", nil))
+	types.NewNamed(pkg.Scope().Lookup("Size").(*types.TypeName), types.Typ[types.String], nil)
+	pkg.MarkComplete()
+
+	var (
+		info   = types.Info{Defs: make(map[*ast.Ident]types.Object)}
+		config = types.Config{
+			Importer: importerFunc(func(path string) (*types.Package, error) {
+				if path != importPath {
+					return nil, fmt.Errorf("unexpected import %s", path)
+				}
+
+				return pkg, nil
+			}),
+		}
+	)
+
+	if _, err = config.Check(files[0].Name.Name, fset, files, &info); err != nil {
+		return
+	}
+
+	for _, capacity := range capacities {
+		var name = "Size" + capacityName(capacity)
+
+		var object *types.Const
+		for ident, obj := range info.Defs {
+			if c, ok := obj.(*types.Const); ok && ident.Name == name {
+				object = c
+			}
+		}
+
+		if object == nil {
+			return fmt.Errorf("constant %s not found", name)
+		}
+
+		if err = checkSize(constant.StringVal(object.Val()), capacity); err != nil {
+			return fmt.Errorf("constant %s: %w", name, err)
+		}
+	}
+
+	return
+}
+
+// checkSize 校验size的头及填充
+func checkSize(size string, capacity uint32) (err error) {
+	if len(size) != headerSize+int(capacity) {
+		return errors.New("invalid length")
+	}
+
+	var h Header
+	if _, err = binary.Decode([]byte(size[:headerSize]), binary.BigEndian, &h.header); err != nil {
+		return
+	}
+
+	if err = h.verifyHeader(); err != nil {
+		return
+	}
+
+	if h.DataCap != capacity || h.DataLen != 0 {
+		return errors.New("invalid header")
+	}
+
+	if strings.Trim(size[headerSize:], "0") != "" {
+		return errors.New("invalid filler")
+	}
+
+	return
+}
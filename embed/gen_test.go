@@ -0,0 +1,70 @@
+package embed
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseCapacity(t *testing.T) {
+	var cases = map[string]uint32{
+		"4096":  4096,
+		"3KB":   3 * 1024,
+		"100kb": 100 * 1024,
+		"48MB":  48 * 1024 * 1024,
+		"1GB":   1024 * 1024 * 1024,
+	}
+
+	for s, capacity := range cases {
+		if n, err := ParseCapacity(s); err != nil || n != capacity {
+			t.Fatal("parse capacity error:", s, n, err)
+		}
+	}
+
+	for _, s := range []string{"", "0", "KB", "-1KB", "1.5MB", "2GB", "1TB"} {
+		if _, err := ParseCapacity(s); err == nil {
+			t.Fatal("parse invalid capacity should fail:", s)
+		}
+	}
+}
+
+func TestGenerateSizes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateSizes(&buf, "config", 3*1024, 1000, 100*1024, 3*1024); err != nil {
+		t.Fatal(err)
+	}
+
+	var source = buf.String()
+	for _, name := range []string{"package config", "Size3KB ", "Size1000B ", "Size100KB "} {
+		if !strings.Contains(source, name) {
+			t.Fatal("generated source error:", name, source)
+		}
+	}
+
+	// 预定义的常量同样满足校验
+	if err := checkSize(string(Size1KB), 1024); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSize(string(Size256KB), 256*1024); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSize(string(Size1KB), 2048); err == nil {
+		t.Fatal("check mismatched size should fail")
+	}
+
+	if err := GenerateSizes(&buf, "config"); err == nil {
+		t.Fatal("generate without capacity should fail")
+	}
+
+	// 同一包中的多个生成文件不冲突
+	var other bytes.Buffer
+	if err := GenerateSizes(&other, "config", 48*1024*1024); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSizes([]uint32{3 * 1024, 48 * 1024 * 1024}, buf.Bytes(), other.Bytes()); err != nil {
+		t.Fatal("generated files conflict:", err)
+	}
+}
@@ -1,10 +1,13 @@
 package embed
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"strings"
+	"unicode"
 )
 
 const (
@@ -14,19 +17,44 @@ const (
 	headerSize      = 52
 )
 
+// NameTag 块名称标签，紧跟在块数据之后，格式为：Size + NameTag + 名称 + "\x00"
+const NameTag = "\uEEEE\u004E\u004D\uEEEE"
+
+const maxNameLen = 64 // 名称最大长度
+
 type header struct {
 	Magic      uint64 // 魔术标志
 	CRC32      uint32 // CRC32校验
 	DataLen    uint32 // 数据大小
 	DataCap    uint32 // 数据容量
 	DataCRC32  uint32 // 数据CRC32
-	NextOffset uint32 // 下个数据偏移
+	NextOffset uint32 // 链中下个块的头偏移，0表示链尾
 	CreateTime int64  // 首次写入时间
 	UpdateTime int64  // 最后写入时间
-	Reserve1   uint32 // 保留字段1
-	Reserve2   uint32 // 保留字段2
+	Flags      uint32 // 标志位
+	RawLen     uint32 // 原始数据大小（压缩前）
 }
 
+const (
+	flagCompress uint32 = 0x0000000f // 压缩算法
+	flagEncrypt  uint32 = 0x000000f0 // 加密算法
+	flagSigned   uint32 = 0x00000100 // 数据后附带ed25519签名
+	flagSlotted  uint32 = 0x00000200 // 数据存放在A/B槽中
+	flagSlotB    uint32 = 0x00000400 // 当前生效的是B槽
+	flagChained  uint32 = 0x00000800 // 链中的后续块，数据接在上一块之后
+	flagSlots    uint32 = 0x0000f000 // 槽数，0表示槽头不含提交时间的旧A/B槽
+	flagExtSize  uint32 = 0x00ff0000 // v2扩展区大小，单位为extUnit
+	flagVersion  uint32 = 0xff000000 // 头格式版本，0表示v1
+
+	dataFlags  = flagCompress | flagEncrypt | flagSigned
+	knownFlags = dataFlags | flagSlotted | flagSlotB | flagChained | flagSlots | flagExtSize | flagVersion
+)
+
+const (
+	headerVersion = 2  // 支持的最新头格式版本
+	extUnit       = 16 // 扩展区大小的单位
+)
+
 var emptyHeader = header{
 	Magic: binary.BigEndian.Uint64([]byte(magic)),
 	CRC32: 0xf151cd41,
@@ -47,6 +75,101 @@ func (h *header) IsInit() bool {
 	return h.CreateTime != 0
 }
 
+// compression 数据压缩算法
+func (h *header) compression() Compression {
+	return Compression(h.Flags & flagCompress)
+}
+
+// encryption 数据加密算法
+func (h *header) encryption() Encryption {
+	return Encryption(h.Flags & flagEncrypt >> 4)
+}
+
+// signed 存储的数据后附带签名
+func (h *header) signed() bool {
+	return h.Flags&flagSigned != 0
+}
+
+// slotted 数据存放在A/B槽中
+func (h *header) slotted() bool {
+	return h.Flags&flagSlotted != 0
+}
+
+// slots 槽的数量，A/B槽为2个
+func (h *header) slots() int {
+	if n := int(h.Flags & flagSlots >> 12); n > 2 {
+		return n
+	}
+
+	return 2
+}
+
+// chained 链中的后续块，由NextOffset指向
+func (h *header) chained() bool {
+	return h.Flags&flagChained != 0
+}
+
+// version 头格式版本，未记录版本的为v1
+func (h *header) version() int {
+	if v := int(h.Flags & flagVersion >> 24); v > 1 {
+		return v
+	}
+
+	return 1
+}
+
+// extSize 扩展区大小，扩展区位于数据容量的末尾
+func (h *header) extSize() uint32 {
+	return (h.Flags & flagExtSize >> 16) * extUnit
+}
+
+// capacity 数据可用的容量，不含扩展区
+func (h *header) capacity() uint32 {
+	if ext := h.extSize(); ext < h.DataCap {
+		return h.DataCap - ext
+	}
+
+	return 0
+}
+
+// dataHeader 签名及加密认证的头字段：原始大小及数据标志位，篡改头中的原始大小或
+// 压缩、加密方式会使签名或解密失败
+func dataHeader(size, flags uint32) []byte {
+	var data = make([]byte, 8)
+	binary.BigEndian.PutUint32(data[:4], size)
+	binary.BigEndian.PutUint32(data[4:], flags&dataFlags)
+
+	return data
+}
+
+// encoded 存储的数据经过压缩、加密或附带签名
+func (h *header) encoded() bool {
+	return h.Flags&(flagCompress|flagEncrypt|flagSigned) != 0
+}
+
+// size 原始数据大小
+func (h *header) size() uint32 {
+	if h.encoded() {
+		return h.RawLen
+	}
+
+	return h.DataLen
+}
+
+// checksum 计算头crc32
+func (h *header) checksum() (sum uint32, err error) {
+	var clone = *h
+
+	clone.CRC32 = 0
+
+	data, err := clone.Encode()
+	if err != nil {
+		return
+	}
+
+	return crc32.ChecksumIEEE(data), nil
+}
+
 func (h *header) Encode() (data []byte, err error) {
 	var buf = make([]byte, headerSize)
 
@@ -60,32 +183,79 @@ func (h *header) Encode() (data []byte, err error) {
 
 type Header struct {
 	header
-	Offset int64 // 数据偏移
+	Offset     int64  // 数据偏移
+	generation uint32 // 生效的槽的提交代数
+	slot       int    // 生效的槽
 }
 
-func (h *Header) Verify(data []byte) (err error) {
+// checkName 校验名称合法性
+func checkName(name string) error {
+	if name == "" || len(name) > maxNameLen {
+		return fmt.Errorf("invalid block name length: %d", len(name))
+	}
+
+	for _, r := range name {
+		if r <= ' ' || r == 0x7f || r == '\uEEEE' {
+			return fmt.Errorf("invalid block name: %q", name)
+		}
+	}
+
+	// 全部为数字的名称与块编号无法区分
+	if strings.IndexFunc(name, func(r rune) bool { return !unicode.IsNumber(r) }) == -1 {
+		return fmt.Errorf("block name is a number: %q", name)
+	}
+
+	return nil
+}
+
+// parseName 解析块数据后的名称标签，不存在时返回空
+func parseName(data []byte) string {
+	if !bytes.HasPrefix(data, []byte(NameTag)) {
+		return ""
+	}
+
+	data = data[len(NameTag):]
+
+	var index = bytes.IndexByte(data, 0)
+	if index == -1 {
+		return ""
+	}
+
+	if name := string(data[:index]); checkName(name) == nil {
+		return name
+	}
+
+	return ""
+}
+
+// verifyHeader 校验magic及头crc32
+func (h *Header) verifyHeader() (err error) {
 	// 校验magic
 	if h.Magic != emptyHeader.Magic {
 		return errors.New("invalid header magic")
 	}
 
 	// 计算头crc32
-	var (
-		crc32Data   []byte
-		crc32Header = *h
-	)
-	crc32Header.CRC32 = 0
-	if crc32Data, err = crc32Header.Encode(); err != nil {
+	sum, err := h.checksum()
+	if err != nil {
 		return
 	}
 
 	// 校验头crc32
-	if h.CRC32 != crc32.ChecksumIEEE(crc32Data) {
+	if h.CRC32 != sum {
 		return errors.New("invalid header crc32")
 	}
 
+	return
+}
+
+func (h *Header) Verify(data []byte) (err error) {
+	if err = h.verifyHeader(); err != nil {
+		return
+	}
+
 	// 校验数据大小
-	if h.DataLen > h.DataCap || len(data) != int(h.DataLen) {
+	if h.DataLen > h.capacity() || len(data) != int(h.DataLen) {
 		return errors.New("invalid data length")
 	}
 
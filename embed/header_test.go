@@ -15,8 +15,8 @@ func TestHeader_Encode(t *testing.T) {
 		NextOffset: 0,
 		CreateTime: time.Now().Unix(),
 		UpdateTime: 0,
-		Reserve1:   0,
-		Reserve2:   0,
+		Flags:      0,
+		RawLen:     0,
 	}
 
 	if !h.IsInit() {
@@ -43,3 +43,21 @@ func TestHeader_Decode(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestParseName(t *testing.T) {
+	var cases = map[string]string{
+		NameTag + "config\x00tail": "config",
+		NameTag + "config":         "",
+		NameTag + "\x00":           "",
+		NameTag + "a b\x00":        "",
+		"1" + NameTag + "name\x00": "",
+		NameTag + "2024\x00":       "",
+		NameTag + "v2\x00":         "v2",
+	}
+
+	for data, name := range cases {
+		if parseName([]byte(data)) != name {
+			t.Fatalf("parseName(%q) error", data)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+)
+
+// 块索引：记录各个头的偏移、数据容量及名称，写入文件尾部。打开文件时校验索引中的
+// 头仍然存在，校验通过则无需扫描整个文件查找magic。
+type indexEntry struct {
+	Offset  int64  // 头偏移
+	DataCap uint32 // 数据容量
+	Name    string // 名称
+}
+
+// encodeIndex 序列化索引：内容长度、数量，以及各项的偏移、容量、名称长度及名称
+func encodeIndex(content int64, entries []indexEntry) (data []byte, err error) {
+	var buf bytes.Buffer
+
+	if err = binary.Write(&buf, binary.BigEndian, uint64(content)); err != nil {
+		return
+	}
+
+	if err = binary.Write(&buf, binary.BigEndian, uint32(len(entries))); err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if err = binary.Write(&buf, binary.BigEndian, uint64(e.Offset)); err != nil {
+			return
+		}
+
+		if err = binary.Write(&buf, binary.BigEndian, e.DataCap); err != nil {
+			return
+		}
+
+		if err = binary.Write(&buf, binary.BigEndian, uint8(len(e.Name))); err != nil {
+			return
+		}
+
+		buf.WriteString(e.Name)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeIndex(data []byte) (content int64, entries []indexEntry, err error) {
+	var (
+		reader = bytes.NewReader(data)
+		size   uint64
+		count  uint32
+	)
+
+	if err = binary.Read(reader, binary.BigEndian, &size); err != nil {
+		return
+	}
+
+	if err = binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return
+	}
+
+	entries = make([]indexEntry, 0, count)
+
+	for range count {
+		var (
+			e      indexEntry
+			offset uint64
+			length uint8
+		)
+
+		if err = binary.Read(reader, binary.BigEndian, &offset); err != nil {
+			return
+		}
+
+		if err = binary.Read(reader, binary.BigEndian, &e.DataCap); err != nil {
+			return
+		}
+
+		if err = binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return
+		}
+
+		var name = make([]byte, length)
+		if _, err = io.ReadFull(reader, name); err != nil {
+			return
+		}
+
+		e.Offset, e.Name = int64(offset), string(name)
+		entries = append(entries, e)
+	}
+
+	if reader.Len() != 0 {
+		return 0, nil, errors.New("invalid index length")
+	}
+
+	return int64(size), entries, nil
+}
+
+// readIndex 读取并校验索引，索引不存在或已过期时返回false
+func readIndex(file storage) (entries []indexEntry, ok bool) {
+	content, entries := loadIndex(file)
+	if entries == nil {
+		return nil, false
+	}
+
+	// 校验各个头的magic及容量
+	var buf = make([]byte, headerSize)
+	for _, e := range entries {
+		if e.Offset < 0 || e.Offset+headerSize > content {
+			return nil, false
+		}
+
+		if _, err := file.ReadAt(buf, e.Offset); err != nil {
+			return nil, false
+		}
+
+		var h header
+		if _, err := binary.Decode(buf, binary.BigEndian, &h); err != nil {
+			return nil, false
+		}
+
+		if h.Magic != emptyHeader.Magic || h.DataCap != e.DataCap {
+			return nil, false
+		}
+	}
+
+	return entries, true
+}
+
+// loadIndex 读取索引，不校验索引中的头，索引不存在或内容长度不一致时为nil
+func loadIndex(file storage) (content int64, entries []indexEntry) {
+	sections, content, err := readTrailers(file)
+	if err != nil {
+		return
+	}
+
+	var index = slices.IndexFunc(sections, func(s section) bool {
+		return s.kind == trailerIndex
+	})
+	if index == -1 {
+		return
+	}
+
+	data, err := readSection(file, sections[index])
+	if err != nil {
+		return
+	}
+
+	size, entries, err := decodeIndex(data)
+	if err != nil || size != content {
+		return content, nil
+	}
+
+	return
+}
+
+// scanHeaders 扫描尾部之前的内容查找头，ELF文件只扫描数据段
+func (e *Embed) scanHeaders() (content int64, headers []Header, err error) {
+	if _, content, err = readTrailers(e.file); err != nil {
+		return
+	}
+
+	var sections = e.dataSections(content)
+	if len(sections) == 0 {
+		sections = []dataSection{{offset: 0, size: content}}
+	}
+
+	var offsets []int64
+	for _, s := range sections {
+		var found []int64
+		if found, err = getOffset(io.NewSectionReader(e.file, s.offset, s.size), []byte(magic)); err != nil {
+			return
+		}
+
+		for _, offset := range found {
+			offsets = append(offsets, s.offset+offset)
+		}
+	}
+
+	headers, err = getHeaders(e.file, offsets)
+
+	return
+}
+
+// WriteIndex scans the file and writes an index of its blocks into a
+// trailer at the end of the file. Later Blocks calls validate the index
+// cheaply and use it instead of scanning the whole file, a missing or
+// stale index falls back to scanning. The trailer does not affect running
+// the executable.
+func (e *Embed) WriteIndex() (err error) {
+	content, headers, err := e.scanHeaders()
+	if err != nil {
+		return
+	}
+
+	var entries = make([]indexEntry, 0, len(headers))
+	for _, h := range headers {
+		// 只记录头完整的块，头损坏但A/B槽可恢复的同样记录
+		if h.verifyHeader() != nil {
+			if _, ok := recoverSlot(e.file, h); !ok {
+				continue
+			}
+		}
+
+		var name string
+		if name, err = blockName(e.file, h); err != nil {
+			return
+		}
+
+		entries = append(entries, indexEntry{Offset: h.Offset - headerSize, DataCap: h.DataCap, Name: name})
+	}
+
+	data, err := encodeIndex(content, entries)
+	if err != nil {
+		return
+	}
+
+	if err = writeTrailer(e.file, trailerIndex, 0, bytes.NewReader(data)); err != nil {
+		return
+	}
+
+	e.index = entries
+
+	return
+}
+
+// RemoveIndex removes the index written by WriteIndex.
+func (e *Embed) RemoveIndex() (err error) {
+	if err = writeTrailer(e.file, trailerIndex, 0, nil); err != nil {
+		return
+	}
+
+	e.index = nil
+
+	return
+}
+
+// Indexed reports whether blocks are located by a valid index.
+func (e *Embed) Indexed() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.indexed()
+}
+
+// indexed 首次调用时读取索引，调用方持有锁
+func (e *Embed) indexed() bool {
+	if e.index == nil {
+		e.index, _ = readIndex(e.file)
+	}
+
+	return e.index != nil
+}
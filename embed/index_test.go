@@ -0,0 +1,112 @@
+package embed
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEmbed_WriteIndex(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1", Size2KB+NameTag+"config\x00", Size1KB+"3")
+
+	origin, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if emd.Indexed() {
+		t.Fatal("file without index should not be indexed")
+	}
+
+	if err = emd.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后使用索引
+	reopen, err := Open(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopen.Close()
+
+	if !reopen.Indexed() {
+		t.Fatal("file with index should be indexed")
+	}
+
+	blocks, err := reopen.Blocks()
+	if err != nil || len(blocks) != 3 || blocks[1].Name() != "config" || blocks[1].Cap() != 2048 {
+		t.Fatal("indexed blocks error:", err, blocks)
+	}
+
+	if _, err = blocks[2].Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = reopen.Blocks(); err != nil || readString(t, blocks[2]) != "data" {
+		t.Fatal("indexed write error:", err, blocks)
+	}
+
+	// 头被破坏后索引过期，回退到扫描
+	if _, err = reopen.file.WriteAt([]byte("broken"), blocks[0].header.Offset-headerSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if stale, err := Open(emd.name); err != nil || stale.Indexed() {
+		t.Fatal("stale index should not be used:", err)
+	} else if blocks, err = stale.Blocks(); err != nil || len(blocks) != 2 {
+		t.Fatal("scan blocks error:", err, blocks)
+	}
+
+	// 删除索引后恢复原文件长度
+	if err = emd.RemoveIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(emd.name)
+	if err != nil || len(data) != len(origin) {
+		t.Fatal("remove index error:", err, len(data), len(origin))
+	}
+}
+
+func TestWriteTrailer(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	// 类型大的尾部位于前面
+	if err := writeTrailer(emd.file, trailerIndex, 0, strings.NewReader("index")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeTrailer(emd.file, 2, 0, strings.NewReader("payload")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 保留前缀并追加
+	if err := writeTrailer(emd.file, 2, 4, strings.NewReader("-more")); err != nil {
+		t.Fatal(err)
+	}
+
+	sections, content, err := readTrailers(emd.file)
+	if err != nil || len(sections) != 2 || sections[0].kind != 2 || sections[1].kind != trailerIndex {
+		t.Fatal("read trailers error:", err, sections)
+	}
+
+	for i, expect := range []string{"payl-more", "index"} {
+		if data, err := readSection(emd.file, sections[i]); err != nil || !bytes.Equal(data, []byte(expect)) {
+			t.Fatal("read section error:", err, string(data))
+		}
+	}
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 1 {
+		t.Fatal("blocks with trailers error:", err, blocks)
+	}
+
+	if err = writeTrailer(emd.file, 2, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if sections, _, err = readTrailers(emd.file); err != nil || len(sections) != 1 || sections[0].offset != content {
+		t.Fatal("remove trailer error:", err, sections)
+	}
+}
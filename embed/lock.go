@@ -0,0 +1,83 @@
+package embed
+
+import (
+	"errors"
+	"os"
+)
+
+// Lock places an advisory lock on the file, so processes modifying the same
+// file, such as two embed imports, do not interleave. An exclusive lock
+// conflicts with any other lock, a shared lock only with exclusive ones.
+// Lock blocks until the lock is acquired, which is held until Unlock or
+// Close and kept across Update. The file is reopened when it was replaced
+// while waiting, so Lock should be called before Blocks. Lock does nothing
+// on platforms without flock.
+func (e *Embed) Lock(exclusive bool) (err error) {
+	var how = lockShared
+	if exclusive {
+		how = lockExclusive
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for {
+		if err = flock(e.file, how); err != nil {
+			return
+		}
+
+		// 等待期间文件可能被其他进程的Update替换，需锁定新文件
+		var replaced bool
+		if replaced, err = e.replaced(); err != nil || !replaced {
+			break
+		}
+
+		file, err := os.OpenFile(e.name, e.flag, 0644)
+		if err != nil {
+			return err
+		}
+
+		_ = e.file.Close()
+		e.file, e.index = file, nil
+		e.mapping.replace(file, e.flag)
+	}
+
+	if err == nil {
+		e.flock = how
+	}
+
+	return
+}
+
+// Unlock releases the lock placed by Lock.
+func (e *Embed) Unlock() (err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.flock == 0 {
+		return errors.New("file is not locked")
+	}
+
+	if err = flock(e.file, lockNone); err != nil {
+		return
+	}
+
+	e.flock = 0
+
+	return
+}
+
+// replaced 文件路径是否已指向其他文件
+func (e *Embed) replaced() (_ bool, err error) {
+	opened, err := e.file.Stat()
+	if err != nil {
+		return
+	}
+
+	current, err := os.Stat(e.name)
+	if err != nil {
+		return
+	}
+
+	return !os.SameFile(opened, current), nil
+}
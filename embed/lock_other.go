@@ -0,0 +1,14 @@
+//go:build !unix
+
+package embed
+
+const (
+	lockShared = iota + 1
+	lockExclusive
+	lockNone
+)
+
+// flock 不支持flock的平台不加锁
+func flock(storage, int) error {
+	return nil
+}
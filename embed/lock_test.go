@@ -0,0 +1,147 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBlock_Concurrent(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00")
+
+	block, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		a  = bytes.Repeat([]byte("a"), 512)
+		b  = bytes.Repeat([]byte("b"), 512)
+		wg sync.WaitGroup
+	)
+	if _, err = block.Write(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// 读取不会看到写入一半的数据
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				data, err := io.ReadAll(block.NewReader())
+				if err != nil || !bytes.Equal(data, a) && !bytes.Equal(data, b) {
+					t.Error("read error:", err, len(data))
+					return
+				}
+
+				if _, err = emd.Blocks(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for j := 0; j < 100; j++ {
+		var data = a
+		if j%2 == 0 {
+			data = b
+		}
+
+		if _, err = block.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestEmbed_Lock(t *testing.T) {
+	var emd = openTestFile(t, Size1KB)
+
+	other, err := Open(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	if err = emd.Lock(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// 共享锁与排它锁冲突
+	var locked = make(chan error)
+	go func() {
+		locked <- other.Lock(false)
+	}()
+
+	select {
+	case err = <-locked:
+		t.Fatal("lock should block:", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 持有锁时替换文件，锁转移到新文件
+	if err = emd.Update(func(*Embed) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = emd.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = <-locked; err != nil {
+		t.Fatal(err)
+	}
+
+	if replaced, err := other.replaced(); err != nil || replaced {
+		t.Fatal("lock should reopen the replaced file:", err, replaced)
+	}
+
+	if err = other.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlock_ConcurrentSettings(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00")
+
+	block, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = block.SetRevisions(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// 设置与写入并发，由-race检查
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 100; i++ {
+			if err := block.SetRevisions(3); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, err = block.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+
+	if revisions, err := block.Revisions(); err != nil || len(revisions) != 3 {
+		t.Fatal("revisions:", len(revisions), err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package embed
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	lockShared    = syscall.LOCK_SH
+	lockExclusive = syscall.LOCK_EX
+	lockNone      = syscall.LOCK_UN
+)
+
+// flock 加锁或解锁，被信号中断时重试
+func flock(file storage, how int) (err error) {
+	f, ok := file.(interface{ Fd() uintptr })
+	if !ok {
+		return errors.New("file does not support locking")
+	}
+
+	for {
+		if err = syscall.Flock(int(f.Fd()), how); !errors.Is(err, syscall.EINTR) {
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package embed
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+var errNoMmap = errors.New("mmap not supported")
+
+// mapping 只读打开的文件的内存映射，同一文件的块共用。读写打开的文件不映射，
+// 避免切片绕过块的锁看到并发的写入；文件被Update替换后映射新的文件，映射在
+// 进程退出前不释放，已返回的切片始终有效
+type mapping struct {
+	lock sync.Mutex
+	file storage // 可映射的文件，读写打开时为nil
+	data []byte  // nil表示尚未映射
+}
+
+// newMapping 创建文件的映射，flag为文件的打开方式
+func newMapping(file storage, flag int) *mapping {
+	var m = new(mapping)
+	m.replace(file, flag)
+	return m
+}
+
+// replace 文件被替换后改为映射新的文件，旧的映射保留
+func (m *mapping) replace(file storage, flag int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.file, m.data = nil, nil
+	if flag == os.O_RDONLY {
+		m.file = file
+	}
+}
+
+// bytes 返回文件的映射，首次调用时映射，file不是可映射的文件时返回errNoMmap
+func (m *mapping) bytes(file storage) (_ []byte, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.file == nil || m.file != file {
+		return nil, errNoMmap
+	}
+
+	if m.data == nil {
+		if m.data, err = mmap(file); err != nil {
+			return
+		}
+	}
+
+	return m.data, nil
+}
+
+// Bytes returns the block data as a read-only slice, which must not be
+// modified. Data stored as it is in one unslotted block of a file opened
+// read-only, such as the running executable, is sliced from a memory
+// mapping of the file without a copy; such files are only changed by
+// replacing them with Update, and the mapping is kept until the process
+// exits, so the slice stays valid after Update and Close. All other data,
+// including every block of a file opened for writing, is read into a new
+// slice.
+func (b *Block) Bytes() (data []byte, err error) {
+	defer b.rlock()()
+
+	var size = int64(b.len())
+	if b.direct() && !b.chained() && !b.header.slotted() && b.mapping != nil {
+		if m, err := b.mapping.bytes(b.file); err == nil {
+			if offset := b.offset(); offset+size <= int64(len(m)) {
+				return m[offset : offset+size : offset+size], nil
+			}
+		}
+	}
+
+	// 无法映射或可能被写入时复制
+	data = make([]byte, size)
+	if _, err = b.read(data, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return data, nil
+}
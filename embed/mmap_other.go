@@ -0,0 +1,8 @@
+//go:build !unix
+
+package embed
+
+// mmap 不支持内存映射的平台，Bytes回退为复制
+func mmap(storage) ([]byte, error) {
+	return nil, errNoMmap
+}
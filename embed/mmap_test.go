@@ -0,0 +1,174 @@
+package embed
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestBlock_Bytes(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00", Size1KB+NameTag+"zip\x00")
+
+	config, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = config.Write([]byte("config data")); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := config.Bytes()
+	if err != nil || string(a) != "config data" {
+		t.Fatalf("bytes: %q, %v", a, err)
+	}
+
+	// 读写打开的文件可能被写入，复制
+	b, err := config.Bytes()
+	if err != nil || &a[0] == &b[0] {
+		t.Fatal("bytes of a writable file are shared:", err)
+	}
+
+	// 只读打开的文件映射，由调用方共用，不复制
+	file, err := os.Open(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ro = newEmbed(file, emd.name, os.O_RDONLY)
+	if config, err = ro.Lookup("config"); err != nil {
+		t.Fatal(err)
+	}
+
+	if a, err = config.Bytes(); err != nil || string(a) != "config data" {
+		t.Fatalf("mapped bytes: %q, %v", a, err)
+	}
+
+	if b, err = config.Bytes(); err != nil || &a[0] != &b[0] || cap(b) != len(b) {
+		t.Fatal("bytes of a read-only file are not shared:", err)
+	}
+
+	// 关闭后映射保留，切片仍然有效
+	if err = ro.Close(); err != nil || string(a) != "config data" {
+		t.Fatalf("bytes after close: %q, %v", a, err)
+	}
+
+	// 压缩的数据解码到新的切片
+	zip, err := emd.Lookup("zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = zip.SetCompression(CompressGzip); err != nil {
+		t.Fatal(err)
+	}
+
+	var data = bytes.Repeat([]byte("zip data "), 64)
+	if _, err = zip.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err = zip.Bytes(); err != nil || !bytes.Equal(b, data) {
+		t.Fatalf("compressed bytes: %d, %v", len(b), err)
+	}
+
+	// 无法映射的来源回退为复制
+	content, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config, err = OpenReader(bytes.NewReader(content), int64(len(content))).Lookup("config"); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err = config.Bytes(); err != nil || string(b) != "config data" {
+		t.Fatalf("copied bytes: %q, %v", b, err)
+	}
+}
+
+func TestBlock_BytesSlotted(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00")
+
+	config, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.SetAtomic(true)
+
+	if _, err = config.Write([]byte("slot data")); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ro = newEmbed(file, emd.name, os.O_RDONLY)
+	defer ro.Close()
+
+	if config, err = ro.Lookup("config"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 槽中的数据即使只读打开也复制
+	a, err := config.Bytes()
+	if err != nil || string(a) != "slot data" {
+		t.Fatalf("slotted bytes: %q, %v", a, err)
+	}
+
+	if b, err := config.Bytes(); err != nil || &a[0] == &b[0] {
+		t.Fatal("bytes of a slotted block are shared:", err)
+	}
+}
+
+func TestMallocBytes(t *testing.T) {
+	resetSource(t)
+
+	var (
+		config = Size1KB + NameTag + "config\x00"
+		extra  = Size1KB + NameTag + "extra\x00"
+		emd    = openTestFile(t, config, extra)
+	)
+
+	found, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = found.Write([]byte("config data")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = SetSource(emd.name); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MallocBytes(config)
+	if err != nil || string(data) != "config data" {
+		t.Fatalf("malloc bytes: %q, %v", data, err)
+	}
+
+	block, err := Malloc(extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Update(false, func() error {
+		_, err := block.Write([]byte("updated"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 替换文件后读取新的文件，之前的切片仍然有效
+	if updated, err := block.Bytes(); err != nil || string(updated) != "updated" {
+		t.Fatalf("bytes after update: %q, %v", updated, err)
+	}
+
+	if string(data) != "config data" {
+		t.Fatalf("bytes before update: %q", data)
+	}
+}
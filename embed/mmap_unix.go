@@ -0,0 +1,26 @@
+//go:build unix
+
+package embed
+
+import (
+	"syscall"
+)
+
+// mmap 只读映射整个文件
+func mmap(file storage) (data []byte, err error) {
+	f, ok := file.(interface{ Fd() uintptr })
+	if !ok {
+		return nil, errNoMmap
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	if info.Size() == 0 || int64(int(info.Size())) != info.Size() {
+		return nil, errNoMmap
+	}
+
+	return syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package embed
+
+import (
+	"os"
+)
+
+// chown 不支持属主的平台忽略
+func chown(*os.File, os.FileInfo) error {
+	return nil
+}
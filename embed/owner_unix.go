@@ -0,0 +1,17 @@
+//go:build unix
+
+package embed
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown 保持原文件的属主
+func chown(file *os.File, info os.FileInfo) (err error) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return file.Chown(int(stat.Uid), int(stat.Gid))
+	}
+
+	return
+}
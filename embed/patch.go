@@ -0,0 +1,83 @@
+package embed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Find returns the offsets of the non-overlapping occurrences of pattern
+// in the file.
+func (e *Embed) Find(pattern []byte) (offsets []int64, err error) {
+	if len(pattern) == 0 {
+		return nil, errors.New("empty pattern")
+	}
+
+	info, err := e.file.Stat()
+	if err != nil {
+		return
+	}
+
+	return getOffset(io.NewSectionReader(e.file, 0, info.Size()), pattern)
+}
+
+// CheckPatch reports whether Patch would modify the file, returning the
+// offsets of the occurrences of old even if it would not.
+func (e *Embed) CheckPatch(old, new []byte, count int, pad bool) (offsets []int64, err error) {
+	// 校验长度，替换内容不能改变文件布局
+	switch {
+	case len(new) > len(old):
+		return nil, fmt.Errorf("replacement longer than the original: %d > %d", len(new), len(old))
+	case len(new) < len(old) && !pad:
+		return nil, fmt.Errorf("replacement shorter than the original without padding: %d < %d", len(new), len(old))
+	}
+
+	if offsets, err = e.Find(old); err != nil {
+		return
+	}
+
+	if len(offsets) != count {
+		return offsets, fmt.Errorf("found %d occurrences, expected %d", len(offsets), count)
+	}
+
+	// 不能修改块的头及数据
+	blocks, err := e.Blocks()
+	if err != nil {
+		return
+	}
+
+	for _, offset := range offsets {
+		for _, b := range blocks {
+			for _, h := range append([]Header{b.header}, b.chain...) {
+				var start, end = h.Offset - headerSize, h.Offset + int64(h.DataCap)
+				if offset < end && offset+int64(len(old)) > start {
+					return offsets, fmt.Errorf("occurrence at %#x overlaps block at %#x", offset, start)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Patch replaces the occurrences of old in the file with new, which is
+// useful to stamp strings such as build IDs into binaries without blocks.
+// new must be as long as old, or shorter if pad is set and then padded
+// with NUL bytes. The file is modified only if old occurs exactly count
+// times and no occurrence overlaps a block. The offsets of the
+// occurrences are returned even if the file is not modified.
+func (e *Embed) Patch(old, new []byte, count int, pad bool) (offsets []int64, err error) {
+	if offsets, err = e.CheckPatch(old, new, count, pad); err != nil {
+		return
+	}
+
+	var data = append(bytes.Clone(new), make([]byte, len(old)-len(new))...)
+	for _, offset := range offsets {
+		if _, err = e.file.WriteAt(data, offset); err != nil {
+			return
+		}
+	}
+
+	return offsets, e.file.Sync()
+}
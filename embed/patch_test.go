@@ -0,0 +1,62 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestEmbed_Patch(t *testing.T) {
+	var emd = openTestFile(t, Size1KB, Size("BUILD-ID-xxxxxxxx"))
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 1 {
+		t.Fatal("blocks error:", err, len(blocks))
+	}
+
+	if _, err = blocks[0].Write([]byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 长度校验
+	if _, err = emd.Patch([]byte("BUILD-ID-xxxxxxxx"), []byte("BUILD-ID-1"), 1, false); err == nil {
+		t.Fatal("shorter replacement without padding should fail")
+	}
+
+	if _, err = emd.Patch([]byte("BUILD"), []byte("BUILD-ID"), 1, true); err == nil {
+		t.Fatal("longer replacement should fail")
+	}
+
+	// 次数不符时不修改
+	offsets, err := emd.Patch([]byte("padding"), []byte("PADDING"), 1, false)
+	if err == nil || len(offsets) != 2 {
+		t.Fatal("count mismatch should fail:", err, offsets)
+	}
+
+	// 不能修改块数据
+	if _, err = emd.Patch([]byte("secret"), []byte("public"), 1, false); err == nil {
+		t.Fatal("patching block data should fail")
+	}
+
+	if offsets, err = emd.Patch([]byte("BUILD-ID-xxxxxxxx"), []byte("BUILD-ID-1"), 1, true); err != nil || len(offsets) != 1 {
+		t.Fatal("patch error:", err, offsets)
+	}
+
+	data, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(data, []byte("BUILD-ID-1\x00\x00\x00\x00\x00\x00\x00\x00padding")) || bytes.Contains(data, []byte("PADDING")) {
+		t.Fatal("patched data error")
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("blocks after patch error:", err, len(blocks))
+	}
+
+	if data, err = io.ReadAll(blocks[0].NewReader()); err != nil || string(data) != "secret" {
+		t.Fatal("block data error:", err, string(data))
+	}
+}
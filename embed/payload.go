@@ -0,0 +1,380 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+)
+
+// 追加载荷：无需预留空间，直接追加到文件末尾的尾部中。尾部数据依次为各个载荷、
+// 载荷索引及固定长度的载荷尾部（索引长度及索引crc32），程序本身不受影响。
+const payloadFooterSize = 8
+
+// payloadEntry 载荷索引项
+type payloadEntry struct {
+	Name    string
+	Offset  int64  // 相对尾部数据的偏移
+	Size    int64  // 长度
+	CRC32   uint32 // crc32
+	ModTime int64  // 修改时间
+}
+
+// Payload is a file appended to the end of a binary by AddPayload.
+type Payload struct {
+	file    storage
+	name    string
+	offset  int64
+	size    int64
+	sum     uint32
+	modTime time.Time
+}
+
+// Name returns the payload name, which is a valid fs path.
+func (p Payload) Name() string {
+	return p.name
+}
+
+// Size returns the payload length.
+func (p Payload) Size() int64 {
+	return p.size
+}
+
+// ModTime returns the modification time recorded when the payload was added.
+func (p Payload) ModTime() time.Time {
+	return p.modTime
+}
+
+func (p Payload) String() string {
+	return fmt.Sprintf("%s\t%d\t%s", p.name, p.size, p.modTime.Format(time.RFC3339))
+}
+
+// NewReader returns a reader of the payload data.
+func (p Payload) NewReader() *io.SectionReader {
+	return io.NewSectionReader(p.file, p.offset, p.size)
+}
+
+// Verify checks the payload data against its checksum.
+func (p Payload) Verify() (err error) {
+	var hash = crc32.NewIEEE()
+	if _, err = io.Copy(hash, p.NewReader()); err != nil {
+		return
+	}
+
+	if hash.Sum32() != p.sum {
+		return fmt.Errorf("payload %s checksum mismatch", p.name)
+	}
+
+	return
+}
+
+// payloadFields 索引项中名称之后的定长字段
+type payloadFields struct {
+	Offset  uint64
+	Size    uint64
+	CRC32   uint32
+	ModTime int64
+}
+
+func encodePayloads(entries []payloadEntry) (data []byte, err error) {
+	var buf bytes.Buffer
+
+	if err = binary.Write(&buf, binary.BigEndian, uint32(len(entries))); err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if err = binary.Write(&buf, binary.BigEndian, uint16(len(e.Name))); err != nil {
+			return
+		}
+
+		buf.WriteString(e.Name)
+
+		var fields = payloadFields{Offset: uint64(e.Offset), Size: uint64(e.Size), CRC32: e.CRC32, ModTime: e.ModTime}
+		if err = binary.Write(&buf, binary.BigEndian, fields); err != nil {
+			return
+		}
+	}
+
+	// 载荷尾部
+	var footer = make([]byte, payloadFooterSize)
+	binary.BigEndian.PutUint32(footer, uint32(buf.Len()))
+	binary.BigEndian.PutUint32(footer[4:], crc32.ChecksumIEEE(buf.Bytes()))
+
+	buf.Write(footer)
+
+	return buf.Bytes(), nil
+}
+
+func decodePayloads(data []byte) (entries []payloadEntry, err error) {
+	var (
+		reader = bytes.NewReader(data)
+		count  uint32
+	)
+
+	if err = binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return
+	}
+
+	entries = make([]payloadEntry, 0, count)
+	for range count {
+		var (
+			e      payloadEntry
+			length uint16
+		)
+
+		if err = binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return
+		}
+
+		var name = make([]byte, length)
+		if _, err = io.ReadFull(reader, name); err != nil {
+			return
+		}
+
+		var fields payloadFields
+		if err = binary.Read(reader, binary.BigEndian, &fields); err != nil {
+			return
+		}
+
+		e.Name, e.Offset, e.Size, e.CRC32, e.ModTime = string(name), int64(fields.Offset), int64(fields.Size), fields.CRC32, fields.ModTime
+		entries = append(entries, e)
+	}
+
+	if reader.Len() != 0 {
+		return nil, errors.New("invalid payload index length")
+	}
+
+	return
+}
+
+// readPayloads 读取载荷索引，records为各载荷占用的长度
+func readPayloads(file storage) (s section, entries []payloadEntry, records int64, err error) {
+	sections, _, err := readTrailers(file)
+	if err != nil {
+		return
+	}
+
+	var index = slices.IndexFunc(sections, func(s section) bool {
+		return s.kind == trailerPayload
+	})
+	if index == -1 {
+		return
+	}
+
+	if s = sections[index]; s.length < payloadFooterSize {
+		return s, nil, 0, errors.New("invalid payload trailer")
+	}
+
+	var footer = make([]byte, payloadFooterSize)
+	if _, err = file.ReadAt(footer, s.offset+s.length-payloadFooterSize); err != nil {
+		return
+	}
+
+	var size = int64(binary.BigEndian.Uint32(footer))
+	if records = s.length - payloadFooterSize - size; records < 0 {
+		return s, nil, 0, errors.New("invalid payload index length")
+	}
+
+	var data = make([]byte, size)
+	if _, err = file.ReadAt(data, s.offset+records); err != nil {
+		return
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(footer[4:]) {
+		return s, nil, 0, errors.New("invalid payload index checksum")
+	}
+
+	if entries, err = decodePayloads(data); err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if e.Offset < 0 || e.Size < 0 || e.Offset+e.Size > records {
+			return s, nil, 0, fmt.Errorf("invalid payload %s", e.Name)
+		}
+	}
+
+	return
+}
+
+// Payloads returns the payloads appended to the file.
+func (e *Embed) Payloads() (payloads []Payload, err error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	s, entries, _, err := readPayloads(e.file)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		payloads = append(payloads, Payload{
+			file:    e.file,
+			name:    entry.Name,
+			offset:  s.offset + entry.Offset,
+			size:    entry.Size,
+			sum:     entry.CRC32,
+			modTime: time.Unix(entry.ModTime, 0),
+		})
+	}
+
+	return
+}
+
+// Payload returns the payload with name.
+func (e *Embed) Payload(name string) (_ *Payload, err error) {
+	payloads, err := e.Payloads()
+	if err != nil {
+		return
+	}
+
+	for _, p := range payloads {
+		if p.name == name {
+			return &p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("payload %s not found", name)
+}
+
+// AddPayload appends the data read from r to the end of the file as a
+// payload named name, which must be a valid fs path not added before. The
+// program in the file keeps running unmodified.
+func (e *Embed) AddPayload(name string, r io.Reader, modTime time.Time) (err error) {
+	if !fs.ValidPath(name) || name == "." || len(name) > 0xffff {
+		return fmt.Errorf("invalid payload name %q", name)
+	}
+
+	_, entries, records, err := readPayloads(e.file)
+	if err != nil {
+		return
+	}
+
+	if slices.ContainsFunc(entries, func(e payloadEntry) bool { return e.Name == name }) {
+		return fmt.Errorf("payload %s already exists", name)
+	}
+
+	// 先读入临时文件，读取失败时不影响原文件
+	temp, err := os.CreateTemp("", "embed-payload-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	var hash = crc32.NewIEEE()
+	size, err := io.Copy(io.MultiWriter(temp, hash), r)
+	if err != nil {
+		return
+	}
+
+	if _, err = temp.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	index, err := encodePayloads(append(entries, payloadEntry{
+		Name:    name,
+		Offset:  records,
+		Size:    size,
+		CRC32:   hash.Sum32(),
+		ModTime: modTime.Unix(),
+	}))
+	if err != nil {
+		return
+	}
+
+	// 保留已有的载荷，追加新载荷及索引
+	return writeTrailer(e.file, trailerPayload, records, io.MultiReader(temp, bytes.NewReader(index)))
+}
+
+// RemovePayload removes the payload with name, the payloads after it are
+// moved forward.
+func (e *Embed) RemovePayload(name string) (err error) {
+	s, entries, _, err := readPayloads(e.file)
+	if err != nil {
+		return
+	}
+
+	var index = slices.IndexFunc(entries, func(e payloadEntry) bool { return e.Name == name })
+	if index == -1 {
+		return fmt.Errorf("payload %s not found", name)
+	}
+
+	entries = slices.Delete(entries, index, index+1)
+	if len(entries) == 0 {
+		return e.StripPayloads()
+	}
+
+	// 剩余载荷依次拼接
+	var (
+		readers = make([]io.Reader, 0, len(entries)+1)
+		offset  int64
+	)
+	for i, entry := range entries {
+		readers = append(readers, io.NewSectionReader(e.file, s.offset+entry.Offset, entry.Size))
+		entries[i].Offset = offset
+		offset += entry.Size
+	}
+
+	data, err := encodePayloads(entries)
+	if err != nil {
+		return
+	}
+
+	// 载荷读入临时文件，避免重写时覆盖尚未读取的数据
+	temp, err := os.CreateTemp("", "embed-payload-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if _, err = io.Copy(temp, io.MultiReader(append(readers, bytes.NewReader(data))...)); err != nil {
+		return
+	}
+
+	if _, err = temp.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	return writeTrailer(e.file, trailerPayload, 0, temp)
+}
+
+// StripPayloads removes all payloads, restoring the file as it was before
+// the first AddPayload.
+func (e *Embed) StripPayloads() error {
+	return writeTrailer(e.file, trailerPayload, 0, nil)
+}
+
+// Payloads returns the payloads appended to the running executable.
+func Payloads() (_ []Payload, err error) {
+	emd, err := self()
+	if err != nil {
+		return
+	}
+
+	return emd.Payloads()
+}
+
+// OpenPayload returns a reader of the payload with name appended to the
+// running executable.
+func OpenPayload(name string) (_ *io.SectionReader, err error) {
+	emd, err := self()
+	if err != nil {
+		return
+	}
+
+	payload, err := emd.Payload(name)
+	if err != nil {
+		return
+	}
+
+	return payload.NewReader(), nil
+}
@@ -0,0 +1,86 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmbed_Payload(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	origin, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = emd.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	var modTime = time.Unix(1700000000, 0)
+	for _, name := range []string{"a.txt", "dir/b.bin", "c"} {
+		if err = emd.AddPayload(name, strings.NewReader("payload "+name), modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = emd.AddPayload("a.txt", strings.NewReader("again"), modTime); err == nil {
+		t.Fatal("add duplicated payload should fail")
+	}
+
+	if err = emd.AddPayload("../x", strings.NewReader("x"), modTime); err == nil {
+		t.Fatal("add invalid payload name should fail")
+	}
+
+	// 块及索引不受影响
+	if !emd.Indexed() {
+		t.Fatal("index should survive payloads")
+	}
+
+	blocks, err := emd.Blocks()
+	if err != nil || len(blocks) != 1 {
+		t.Fatal("blocks with payloads error:", err, blocks)
+	}
+
+	if err = emd.RemovePayload("dir/b.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads, err := emd.Payloads()
+	if err != nil || len(payloads) != 2 || payloads[0].Name() != "a.txt" || payloads[1].Name() != "c" {
+		t.Fatal("payloads error:", err, payloads)
+	}
+
+	for _, p := range payloads {
+		data, err := io.ReadAll(p.NewReader())
+		if err != nil || string(data) != "payload "+p.Name() || !p.ModTime().Equal(modTime) || p.Verify() != nil {
+			t.Fatal("payload data error:", err, p, string(data))
+		}
+	}
+
+	// 损坏的载荷无法通过校验
+	if _, err = emd.file.WriteAt([]byte("X"), payloads[1].offset); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = payloads[1].Verify(); err == nil {
+		t.Fatal("verify broken payload should fail")
+	}
+
+	if err = emd.StripPayloads(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = emd.RemoveIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(emd.name)
+	if err != nil || !bytes.Equal(data, origin) {
+		t.Fatal("strip payloads error:", err)
+	}
+}
@@ -0,0 +1,80 @@
+package embed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// resetHeader 恢复为编译时的状态：Size常量中的空头及'0'填充
+func resetHeader(file storage, h Header) (err error) {
+	var filler = bytes.Repeat([]byte("0"), min(int(h.DataCap), 64*1024))
+	for offset := int64(0); offset < int64(h.DataCap); offset += int64(len(filler)) {
+		var n = min(int64(len(filler)), int64(h.DataCap)-offset)
+		if _, err = file.WriteAt(filler[:n], h.Offset+offset); err != nil {
+			return
+		}
+	}
+
+	data, err := emptyHeaderOf(h.DataCap)
+	if err != nil {
+		return
+	}
+
+	if _, err = file.WriteAt(data, h.Offset-headerSize); err != nil {
+		return
+	}
+
+	return file.Sync()
+}
+
+// Reset restores every block, including the members of chains and the
+// slots of atomic blocks, to the state produced by the Size constants at
+// build time, and removes the trailers appended to the file, such as the
+// index and the payloads. A stamped binary is then byte for byte identical
+// to the unstamped build. Blocks with a corrupt header are left unchanged
+// and reported in the error.
+func (e *Embed) Reset() (count int, err error) {
+	findings, err := e.Fsck()
+	if err != nil {
+		return
+	}
+
+	var errs []error
+	for _, f := range findings {
+		// 非头的magic
+		if errors.Is(f.Err, ErrNotHeader) {
+			continue
+		}
+
+		// 头损坏时容量不可信
+		if f.verifyHeader() != nil && f.Err != nil {
+			errs = append(errs, fmt.Errorf("block at %#x: %w", f.HeaderOffset(), f.Err))
+			continue
+		}
+
+		if err = resetHeader(e.file, f.Header); err != nil {
+			return
+		}
+
+		count++
+	}
+
+	// 删除尾部
+	_, content, err := readTrailers(e.file)
+	if err != nil {
+		return
+	}
+
+	if err = e.file.Truncate(content); err != nil {
+		return
+	}
+
+	if err = e.file.Sync(); err != nil {
+		return
+	}
+
+	e.index = nil
+
+	return count, errors.Join(errs...)
+}
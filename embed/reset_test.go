@@ -0,0 +1,68 @@
+package embed
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmbed_Reset(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00", Size1KB+"2", Size1KB+"3", Size2KB+"4")
+
+	pristine, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 写入、A/B槽、块链、尾部
+	if _, err = blocks[0].Write([]byte("config")); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks[3].SetAtomic(true)
+	if _, err = blocks[3].Write([]byte("atomic")); err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := emd.Chain(&blocks[1], &blocks[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = chain.Write([]byte(strings.Repeat("c", 1500))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = emd.AddPayload("payload", strings.NewReader("payload"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = emd.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := emd.Reset()
+	if err != nil || count != 4 {
+		t.Fatal("reset error:", err, count)
+	}
+
+	data, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, pristine) {
+		t.Fatal("reset file differs from pristine file")
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 4 {
+		t.Fatal("blocks after reset error:", err, len(blocks))
+	}
+}
@@ -0,0 +1,157 @@
+package embed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Revision is a committed revision kept in the slots of an atomic block.
+type Revision struct {
+	Number uint32    // 提交代数，越大越新
+	Time   time.Time // 提交时间
+	Len    uint32    // 数据大小（压缩、加密前）
+	Active bool      // 当前生效的版本
+	slot   int
+}
+
+func (r Revision) String() string {
+	var active string
+	if r.Active {
+		active = "\t*"
+	}
+
+	return fmt.Sprintf("%d\t%d\t%s%s", r.Number, r.Len, r.Time.Format(time.RFC3339), active)
+}
+
+// SetRevisions makes later writes atomic and keep up to n revisions in a
+// ring of n slots, the capacity is divided by n. The oldest revision is
+// overwritten by each write. n must be between 2 and 15, and 2 is the
+// same as SetAtomic(true). Changing the number of slots of an atomic
+// block discards its revisions on the next write, which goes to a slot
+// clear of the current data and fails when there is none.
+func (b *Block) SetRevisions(n int) (err error) {
+	defer b.wlock()()
+
+	if n < 2 || n > maxSlots {
+		return fmt.Errorf("invalid revision count: %d", n)
+	}
+
+	b.atomic, b.revisions = true, n
+
+	return
+}
+
+// Revisions returns the committed revisions of an atomic block, newest
+// first.
+func (b *Block) Revisions() (revisions []Revision, err error) {
+	defer b.rlock()()
+
+	if !b.header.slotted() {
+		return nil, errors.New("block is not atomic")
+	}
+
+	for i, s := range readSlots(b.file, &b.header) {
+		if s == nil {
+			continue
+		}
+
+		var size = s.DataLen
+		if s.Flags&dataFlags != 0 {
+			size = s.RawLen
+		}
+
+		revisions = append(revisions, Revision{
+			Number: s.Generation,
+			Time:   time.Unix(s.Time, 0),
+			Len:    size,
+			Active: i == b.header.slot,
+			slot:   i,
+		})
+	}
+
+	slices.SortFunc(revisions, func(a, b Revision) int {
+		return int(int64(b.Number) - int64(a.Number))
+	})
+
+	return
+}
+
+// revision 指定版本所在的槽
+func (b Block) revision(number uint32) (index int, s slot, err error) {
+	for i, rev := range readSlots(b.file, &b.header) {
+		if rev != nil && rev.Generation == number {
+			return i, *rev, nil
+		}
+	}
+
+	return -1, s, fmt.Errorf("revision %d not found", number)
+}
+
+// OpenRevision returns a reader of the data of revision number, see
+// NewReader.
+func (b *Block) OpenRevision(number uint32) (_ *io.SectionReader, err error) {
+	var unlock = b.rlock()
+
+	if !b.header.slotted() {
+		unlock()
+		return nil, errors.New("block is not atomic")
+	}
+
+	index, s, err := b.revision(number)
+	if err != nil {
+		unlock()
+		return
+	}
+
+	// 以该版本的槽作为生效的槽读取
+	var clone = *b
+	applySlot(&clone.header, index, s)
+	unlock()
+
+	return clone.NewReader(), nil
+}
+
+// Rollback makes revision number active again. The revision is committed
+// anew with the next number, so later revisions are kept until the ring
+// overwrites them and a rollback can itself be rolled back.
+func (b *Block) Rollback(number uint32) (err error) {
+	defer b.wlock()()
+
+	if !b.header.slotted() {
+		return errors.New("block is not atomic")
+	}
+
+	index, s, err := b.revision(number)
+	if err != nil {
+		return
+	}
+
+	if index == b.header.slot {
+		return fmt.Errorf("revision %d is already active", number)
+	}
+
+	// 以新的提交代数重写槽头即提交
+	var h = b.header
+	h.UpdateTime = time.Now().Unix()
+	s.Generation, s.Time = h.generation+1, h.UpdateTime
+
+	if err = writeSlot(b.file, &h, index, s); err != nil {
+		return
+	}
+
+	if err = b.file.Sync(); err != nil {
+		return
+	}
+
+	applySlot(&h, index, s)
+	if err = b.writeHeader(&h); err != nil {
+		return
+	}
+
+	b.header = h
+
+	return
+}
@@ -0,0 +1,134 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBlock_Revisions(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block = &blocks[0]
+	if _, err = block.Revisions(); err == nil {
+		t.Fatal("revisions of plain block should fail")
+	}
+
+	if err = block.SetRevisions(4); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Cap() != (1024-4*slotHeaderSize)/4 {
+		t.Fatal("revision block capacity error:", block.Cap())
+	}
+
+	_ = block.SetCompression(CompressGzip)
+	for _, value := range []string{"v1", "v2", "v3", "v4", "v5"} {
+		if _, err = block.Write([]byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 重新加载后保留最近的4个版本
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload blocks error:", err)
+	}
+
+	block = &blocks[0]
+	revisions, err := block.Revisions()
+	if err != nil || len(revisions) != 4 || revisions[0].Number != 5 || !revisions[0].Active || revisions[3].Number != 2 {
+		t.Fatal("revisions error:", err, revisions)
+	}
+
+	reader, err := block.OpenRevision(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := io.ReadAll(reader); err != nil || string(data) != "v3" {
+		t.Fatal("read revision error:", err, string(data))
+	}
+
+	if err = block.Rollback(5); err == nil {
+		t.Fatal("rollback to active revision should fail")
+	}
+
+	if err = block.Rollback(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || readString(t, blocks[0]) != "v3" {
+		t.Fatal("rollback error:", err)
+	}
+
+	// 回滚后的写入覆盖最旧的版本
+	block = &blocks[0]
+	if _, err = block.Write([]byte("v7")); err != nil {
+		t.Fatal(err)
+	}
+
+	if revisions, err = block.Revisions(); err != nil || len(revisions) != 4 || revisions[0].Number != 7 || revisions[3].Number != 4 {
+		t.Fatal("revisions after rollback error:", err, revisions)
+	}
+
+	if err = block.SetRevisions(16); err == nil {
+		t.Fatal("too many revisions should fail")
+	}
+}
+
+func TestBlock_RevisionsRelayout(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A/B槽，第一个槽生效且数据较长
+	var (
+		block = &blocks[0]
+		data  = strings.Repeat("a", 400)
+	)
+	block.SetAtomic(true)
+	for _, value := range []string{"b", data} {
+		if _, err = block.Write([]byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if block.header.slot != 0 {
+		t.Fatal("active slot error:", block.header.slot)
+	}
+
+	// 改为4个槽：第二个槽与生效的槽重叠，写入第三个槽
+	if err = block.SetRevisions(4); err != nil {
+		t.Fatal(err)
+	}
+
+	if index := block.inactiveSlot(); index != 2 {
+		t.Fatal("relayout slot error:", index)
+	}
+
+	// 写入数据后中断：头未更新，保持旧值
+	if _, err = block.target().WriteAt(bytes.Repeat([]byte("c"), int(block.Cap())), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || readString(t, blocks[0]) != data {
+		t.Fatal("relayout interrupted error:", err)
+	}
+
+	if _, err = block.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || readString(t, blocks[0]) != "new" {
+		t.Fatal("relayout error:", err)
+	}
+}
@@ -0,0 +1,101 @@
+package embed
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var (
+	ErrUnsigned  = errors.New("embed: block is not signed")
+	ErrSignature = errors.New("embed: invalid block signature")
+)
+
+// 签名使用Ed25519ph，对数据的SHA-512摘要签名，支持流式写入
+var signOptions = &ed25519.Options{Hash: crypto.SHA512}
+
+// signedDigest 摘要包含存储的数据及头中的原始大小、数据标志位
+func signedDigest(digest hash.Hash, size, flags uint32) []byte {
+	digest.Write(dataHeader(size, flags))
+
+	return digest.Sum(nil)
+}
+
+// sign 对数据摘要签名
+func sign(key ed25519.PrivateKey, digest []byte) ([]byte, error) {
+	return key.Sign(nil, digest, signOptions)
+}
+
+// verifySignature 校验数据摘要的签名
+func verifySignature(key ed25519.PublicKey, digest, signature []byte) error {
+	if ed25519.VerifyWithOptions(key, digest, signature, signOptions) != nil {
+		return ErrSignature
+	}
+
+	return nil
+}
+
+// ParsePrivateKey parses an ed25519 private key, which is a PEM encoded
+// PKCS #8 key, or a hex encoded seed or private key.
+func ParsePrivateKey(data []byte) (_ ed25519.PrivateKey, err error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if key, ok := key.(ed25519.PrivateKey); ok {
+			return key, nil
+		}
+
+		return nil, errors.New("not an ed25519 private key")
+	}
+
+	key, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return
+	}
+
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("invalid ed25519 private key length: %d", len(key))
+}
+
+// ParsePublicKey parses an ed25519 public key, which is a PEM encoded
+// PKIX key or a hex encoded key.
+func ParsePublicKey(data []byte) (_ ed25519.PublicKey, err error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if key, ok := key.(ed25519.PublicKey); ok {
+			return key, nil
+		}
+
+		return nil, errors.New("not an ed25519 public key")
+	}
+
+	key, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key length: %d", len(key))
+	}
+
+	return key, nil
+}
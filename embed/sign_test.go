@@ -0,0 +1,54 @@
+package embed
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var privates = [][]byte{
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		[]byte(hex.EncodeToString(priv.Seed()) + "\n"),
+		[]byte(hex.EncodeToString(priv)),
+	}
+
+	for _, data := range privates {
+		key, err := ParsePrivateKey(data)
+		if err != nil || !key.Equal(priv) {
+			t.Fatal("ParsePrivateKey error:", err)
+		}
+	}
+
+	if der, err = x509.MarshalPKIXPublicKey(pub); err != nil {
+		t.Fatal(err)
+	}
+
+	var publics = [][]byte{
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		[]byte(hex.EncodeToString(pub)),
+	}
+
+	for _, data := range publics {
+		key, err := ParsePublicKey(data)
+		if err != nil || !key.Equal(pub) {
+			t.Fatal("ParsePublicKey error:", err)
+		}
+	}
+
+	if _, err = ParsePublicKey([]byte("0011")); err == nil {
+		t.Fatal("ParsePublicKey invalid length should fail")
+	}
+}
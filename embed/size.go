@@ -42,6 +42,7 @@ const (
 	Size32KB  = magic + "\x07\x90\x06\x18" + emptyDataLen + "\x00\x00\x80\x00" + emptyHeaderRest + size32KB
 	Size64KB  = magic + "\x3e\xcf\xda\x89" + emptyDataLen + "\x00\x01\x00\x00" + emptyHeaderRest + size64KB
 	Size128KB = magic + "\xb5\x1c\xe4\x90" + emptyDataLen + "\x00\x02\x00\x00" + emptyHeaderRest + size128KB
+	Size256KB = magic + "\x79\xcb\x9e\xe3" + emptyDataLen + "\x00\x04\x00\x00" + emptyHeaderRest + size256KB
 	Size512KB = magic + "\x3b\x14\x6c\x44" + emptyDataLen + "\x00\x08\x00\x00" + emptyHeaderRest + size512KB
 
 	Size1MB  = magic + "\xbe\xab\x89\x0a" + emptyDataLen + "\x00\x10\x00\x00" + emptyHeaderRest + size1MB
@@ -73,8 +74,8 @@ func genSizeDefine(prefix string, size uint32) string {
 			NextOffset: 0,
 			CreateTime: 0,
 			UpdateTime: 0,
-			Reserve1:   0,
-			Reserve2:   0,
+			Flags:      0,
+			RawLen:     0,
 		}
 	)
 
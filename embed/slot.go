@@ -0,0 +1,236 @@
+package embed
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// A/B槽：数据容量平分为两个槽，每个槽以槽头开始，写入时写到未生效的槽，
+// 槽头落盘即提交，读取时以提交代数最大且校验通过的槽为准。版本环为多个槽的
+// A/B槽，写入时覆盖最旧的槽，其余的槽保留历史版本。头中记录槽数的块使用带
+// 提交时间的槽头，未记录槽数的为旧的A/B槽，槽头不含提交时间。
+const (
+	slotMagic            uint32 = 0x534c5432 // "SLT2"
+	slotHeaderSize              = 36
+	legacySlotMagic      uint32 = 0x534c4f54 // "SLOT"，旧的A/B槽
+	legacySlotHeaderSize        = 28
+	maxSlots                    = int(flagSlots >> 12) // 最大槽数
+)
+
+type slot struct {
+	Magic      uint32 // 槽标志
+	Generation uint32 // 提交代数
+	Time       int64  // 提交时间
+	Flags      uint32 // 数据标志位
+	DataLen    uint32 // 数据大小
+	RawLen     uint32 // 原始数据大小
+	DataCRC32  uint32 // 数据CRC32
+	CRC32      uint32 // 槽头CRC32
+}
+
+// Encode 编码槽头，旧的槽头不含提交时间
+func (s *slot) Encode(legacy bool) (data []byte, err error) {
+	var buf = make([]byte, slotHeaderSize)
+
+	n, err := binary.Encode(buf, binary.BigEndian, s)
+	if err != nil {
+		return
+	}
+
+	if legacy {
+		return append(buf[:8:8], buf[16:n]...), nil
+	}
+
+	return buf[:n], nil
+}
+
+// decodeSlot 解码槽头
+func decodeSlot(buf []byte, legacy bool) (s slot, err error) {
+	if legacy {
+		buf = append(append(buf[:8:8], make([]byte, 8)...), buf[8:]...)
+	}
+
+	_, err = binary.Decode(buf, binary.BigEndian, &s)
+
+	return
+}
+
+// checksum 计算槽头crc32
+func (s *slot) checksum(legacy bool) (sum uint32, err error) {
+	var clone = *s
+
+	clone.CRC32 = 0
+
+	data, err := clone.Encode(legacy)
+	if err != nil {
+		return
+	}
+
+	return crc32.ChecksumIEEE(data), nil
+}
+
+// legacySlots 未记录槽数的旧的A/B槽
+func legacySlots(h *Header) bool {
+	return h.Flags&flagSlots == 0
+}
+
+// slotFormat 槽头标志及大小
+func slotFormat(h *Header) (magic uint32, size int64) {
+	if legacySlots(h) {
+		return legacySlotMagic, legacySlotHeaderSize
+	}
+
+	return slotMagic, slotHeaderSize
+}
+
+// slotCap 每个槽的数据容量
+func slotCap(h *Header) uint32 {
+	var (
+		_, size = slotFormat(h)
+		slots   = int64(h.slots())
+		dataCap = int64(h.capacity())
+	)
+
+	if dataCap < size*slots {
+		return 0
+	}
+
+	return uint32((dataCap - size*slots) / slots)
+}
+
+// slotOffset 槽头偏移量
+func slotOffset(h *Header, index int) int64 {
+	var _, size = slotFormat(h)
+
+	return h.Offset + int64(index)*(size+int64(slotCap(h)))
+}
+
+// slotData 槽数据的偏移量
+func slotData(h *Header, index int) int64 {
+	var _, size = slotFormat(h)
+
+	return slotOffset(h, index) + size
+}
+
+// readSlot 读取槽头并校验槽数据
+func readSlot(file storage, h *Header, index int) (s slot, err error) {
+	var (
+		legacy      = legacySlots(h)
+		magic, size = slotFormat(h)
+		buf         = make([]byte, size)
+	)
+
+	if _, err = file.ReadAt(buf, slotOffset(h, index)); err != nil {
+		return
+	}
+
+	if s, err = decodeSlot(buf, legacy); err != nil {
+		return
+	}
+
+	if s.Magic != magic {
+		return s, errors.New("invalid slot magic")
+	}
+
+	sum, err := s.checksum(legacy)
+	if err != nil {
+		return
+	}
+
+	if s.CRC32 != sum {
+		return s, errors.New("invalid slot crc32")
+	}
+
+	if s.DataLen > slotCap(h) || s.Flags&^dataFlags != 0 {
+		return s, errors.New("invalid slot data length")
+	}
+
+	var hash = crc32.NewIEEE()
+	if _, err = io.Copy(hash, io.NewSectionReader(file, slotData(h, index), int64(s.DataLen))); err != nil {
+		return
+	}
+
+	if s.DataCRC32 != hash.Sum32() {
+		return s, errors.New("invalid slot data checksum")
+	}
+
+	return
+}
+
+// writeSlot 写入槽头
+func writeSlot(file storage, h *Header, index int, s slot) (err error) {
+	var legacy = legacySlots(h)
+
+	s.Magic, _ = slotFormat(h)
+	if s.CRC32, err = s.checksum(legacy); err != nil {
+		return
+	}
+
+	data, err := s.Encode(legacy)
+	if err != nil {
+		return
+	}
+
+	_, err = file.WriteAt(data, slotOffset(h, index))
+
+	return
+}
+
+// readSlots 读取各个槽头，无效的槽为nil
+func readSlots(file storage, h *Header) []*slot {
+	var slots = make([]*slot, h.slots())
+	for i := range slots {
+		if s, err := readSlot(file, h, i); err == nil {
+			slots[i] = &s
+		}
+	}
+
+	return slots
+}
+
+// applySlot 以槽更新头的数据标志位、长度及crc32
+func applySlot(h *Header, index int, s slot) {
+	h.Flags = h.Flags&(flagSlots|flagExtSize|flagVersion) | s.Flags | flagSlotted
+	if index == 1 {
+		h.Flags |= flagSlotB
+	}
+
+	h.DataLen = s.DataLen
+	h.RawLen = s.RawLen
+	h.DataCRC32 = s.DataCRC32
+	h.generation = s.Generation
+	h.slot = index
+}
+
+// recoverSlot 以最新提交的槽恢复头，头损坏（写入头时中断）时也能恢复
+func recoverSlot(file storage, h Header) (_ Header, ok bool) {
+	if h.Magic != emptyHeader.Magic {
+		return
+	}
+
+	// 头有效且未使用槽
+	if !h.slotted() && h.verifyHeader() == nil {
+		return
+	}
+
+	var index = -1
+	for i, s := range readSlots(file, &h) {
+		if s != nil && (index == -1 || s.Generation > h.generation) {
+			applySlot(&h, i, *s)
+			index = i
+		}
+	}
+
+	if index == -1 {
+		return
+	}
+
+	var err error
+	if h.CRC32, err = h.checksum(); err != nil {
+		return
+	}
+
+	return h, true
+}
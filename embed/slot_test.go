@@ -0,0 +1,202 @@
+package embed
+
+import (
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func readString(t *testing.T, block Block) string {
+	t.Helper()
+
+	var buf = make([]byte, block.Len())
+	if _, err := block.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf)
+}
+
+func TestBlock_Atomic(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block = &blocks[0]
+	if _, err = block.Write([]byte("plain")); err != nil {
+		t.Fatal(err)
+	}
+
+	block.SetAtomic(true)
+	if block.Cap() != (1024-2*slotHeaderSize)/2 {
+		t.Fatal("atomic block capacity error:", block.Cap())
+	}
+
+	for _, value := range []string{"first", "second", "third"} {
+		if _, err = block.Write([]byte(value)); err != nil {
+			t.Fatal(err)
+		}
+
+		if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+			t.Fatal("reload blocks error:", err)
+		}
+
+		if !blocks[0].Atomic() || readString(t, blocks[0]) != value {
+			t.Fatal("atomic block write error:", blocks[0])
+		}
+	}
+
+	if _, err = block.Write(make([]byte, block.Cap()+1)); err == nil {
+		t.Fatal("write beyond slot capacity should fail")
+	}
+}
+
+func TestBlock_AtomicConvert(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 数据超过容量的一半时，任何槽都与当前数据重叠
+	var (
+		block = &blocks[0]
+		data  = strings.Repeat("p", 800)
+	)
+	if _, err = block.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	block.SetAtomic(true)
+	if err = block.CheckWrite(6); !errors.Is(err, errSlotLayout) {
+		t.Fatal("check overlapping data error:", err)
+	}
+
+	if _, err = block.Write([]byte("atomic")); !errors.Is(err, errSlotLayout) {
+		t.Fatal("convert overlapping data error:", err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || blocks[0].Atomic() || readString(t, blocks[0]) != data {
+		t.Fatal("plain data changed:", err)
+	}
+
+	// 数据不与第二个槽重叠时转换
+	block.SetAtomic(false)
+	if _, err = block.Write([]byte(data[:300])); err != nil {
+		t.Fatal(err)
+	}
+
+	block.SetAtomic(true)
+	if err = block.CheckWrite(block.Cap() + 1); err == nil {
+		t.Fatal("check data larger than the slot should fail")
+	}
+
+	if err = block.CheckWrite(block.Cap()); err != nil {
+		t.Fatal("check data fitting the slot error:", err)
+	}
+
+	if _, err = block.Write([]byte("atomic")); err != nil || !block.Atomic() || block.header.slot != 1 {
+		t.Fatal("convert data error:", err)
+	}
+}
+
+func TestBlock_LegacySlots(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未记录槽数的头使用不含提交时间的旧槽头
+	var (
+		block = &blocks[0]
+		h     = block.header
+		data  = []byte("legacy")
+		s     = slot{Generation: 1, DataLen: uint32(len(data)), DataCRC32: crc32.ChecksumIEEE(data)}
+	)
+	if slotData(&h, 1) != h.Offset+2*legacySlotHeaderSize+int64(1024-2*legacySlotHeaderSize)/2 {
+		t.Fatal("legacy slot offset error:", slotData(&h, 1))
+	}
+
+	if _, err = block.file.WriteAt(data, slotData(&h, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writeSlot(block.file, &h, 1, s); err != nil {
+		t.Fatal(err)
+	}
+
+	applySlot(&h, 1, s)
+	if err = block.writeHeader(&h); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || !blocks[0].Atomic() || readString(t, blocks[0]) != "legacy" {
+		t.Fatal("read legacy slot error:", err)
+	}
+
+	// 继续以旧槽头写入
+	block = &blocks[0]
+	block.SetAtomic(true)
+	if _, err = block.Write([]byte("next")); err != nil || block.header.Flags&flagSlots != 0 {
+		t.Fatal("write legacy slot error:", err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || readString(t, blocks[0]) != "next" {
+		t.Fatal("reload legacy slot error:", err)
+	}
+}
+
+func TestBlock_AtomicRecover(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block = &blocks[0]
+	block.SetAtomic(true)
+	if _, err = block.Write([]byte("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 写入数据后中断：槽头未提交，保持旧值
+	var data = []byte("new")
+	if _, err = block.target().WriteAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || readString(t, blocks[0]) != "old" {
+		t.Fatal("recover uncommitted slot error:", err)
+	}
+
+	// 槽头提交后中断：头未更新，恢复为新值
+	var s = slot{
+		Generation: block.header.generation + 1,
+		DataLen:    uint32(len(data)),
+		DataCRC32:  crc32.ChecksumIEEE(data),
+	}
+	if err = writeSlot(block.file, &block.header, block.inactiveSlot(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || readString(t, blocks[0]) != "new" {
+		t.Fatal("recover committed slot error:", err)
+	}
+
+	// 头损坏：仍可由槽恢复
+	if _, err = block.file.WriteAt([]byte{0xff, 0xff}, block.header.Offset-headerSize+8); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 || readString(t, blocks[0]) != "new" {
+		t.Fatal("recover broken header error:", err)
+	}
+}
@@ -0,0 +1,158 @@
+package embed
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrReadOnly reports a write to a file opened by OpenReader.
+var ErrReadOnly = errors.New("read-only source")
+
+// storage 块所在的文件，*os.File或只读的数据源
+type storage interface {
+	io.ReaderAt
+	io.WriterAt
+	Stat() (fs.FileInfo, error)
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// readOnly 只读的数据源，写入时返回ErrReadOnly
+type readOnly struct {
+	io.ReaderAt
+	size int64
+}
+
+func (readOnly) WriteAt([]byte, int64) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (readOnly) Truncate(int64) error {
+	return ErrReadOnly
+}
+
+func (readOnly) Sync() error {
+	return nil
+}
+
+func (r readOnly) Close() error {
+	if closer, ok := r.ReaderAt.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (r readOnly) Stat() (fs.FileInfo, error) {
+	return readOnlyInfo(r.size), nil
+}
+
+// readOnlyInfo 只读数据源的文件信息
+type readOnlyInfo int64
+
+func (readOnlyInfo) Name() string {
+	return ""
+}
+
+func (i readOnlyInfo) Size() int64 {
+	return int64(i)
+}
+
+func (readOnlyInfo) Mode() fs.FileMode {
+	return 0444
+}
+
+func (readOnlyInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (readOnlyInfo) IsDir() bool {
+	return false
+}
+
+func (readOnlyInfo) Sys() any {
+	return nil
+}
+
+// OpenReader returns an Embed reading the blocks and payloads of size bytes
+// of r, such as a bytes.Reader of a downloaded executable. Writes fail with
+// ErrReadOnly, and Close closes r if it is an io.Closer.
+func OpenReader(r io.ReaderAt, size int64) *Embed {
+	return newEmbed(readOnly{ReaderAt: r, size: size}, "", os.O_RDONLY)
+}
+
+var (
+	embed     *Embed     // 包函数使用的文件，首次使用时打开
+	embedLock sync.Mutex // 保护embed
+)
+
+// self 返回包函数使用的文件，默认为正在运行的可执行文件，打开失败时下次
+// 使用重试
+func self() (_ *Embed, err error) {
+	embedLock.Lock()
+	defer embedLock.Unlock()
+
+	if embed != nil {
+		return embed, nil
+	}
+
+	this, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	if embed, err = Open(this); err != nil {
+		return
+	}
+
+	return embed, nil
+}
+
+// SetSource makes the package functions, such as Malloc and Blocks, use
+// the file at path instead of the running executable, which is useful for
+// tests and tools. It must be called before blocks are allocated, and the
+// previous source is closed.
+func SetSource(path string) (err error) {
+	emd, err := Open(path)
+	if err != nil {
+		return
+	}
+
+	return setSource(emd)
+}
+
+// SetSourceReader is like SetSource but reads size bytes of r, see
+// OpenReader.
+func SetSourceReader(r io.ReaderAt, size int64) error {
+	return setSource(OpenReader(r, size))
+}
+
+func setSource(emd *Embed) (err error) {
+	mallocLock.Lock()
+	defer mallocLock.Unlock()
+
+	// 已分配的块仍指向原文件
+	if len(malloc) > 0 {
+		_ = emd.Close()
+		return errors.New("blocks already malloced")
+	}
+
+	embedLock.Lock()
+	defer embedLock.Unlock()
+
+	if embed != nil {
+		if err = embed.Close(); err != nil {
+			_ = emd.Close()
+			return
+		}
+	}
+
+	embed = emd
+
+	return
+}
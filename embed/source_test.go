@@ -0,0 +1,103 @@
+package embed
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// resetSource 恢复包函数使用的文件
+func resetSource(t *testing.T) {
+	t.Cleanup(func() {
+		mallocLock.Lock()
+		malloc = make(map[string]*Block)
+		mallocLock.Unlock()
+
+		embedLock.Lock()
+		if embed != nil {
+			_ = embed.Close()
+			embed = nil
+		}
+		embedLock.Unlock()
+	})
+}
+
+func TestSetSource(t *testing.T) {
+	resetSource(t)
+
+	var (
+		emd  = openTestFile(t, Size1KB+NameTag+"config\x00")
+		size = Size1KB + NameTag + "config\x00"
+	)
+
+	if err := SetSource(emd.name); err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := MallocNamed("config", size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = SetSource(emd.name); err == nil {
+		t.Fatal("set source after malloc should fail")
+	}
+
+	if err = Update(false, func() error {
+		_, err := block.Write([]byte("updated"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := io.ReadAll(found.NewReader()); err != nil || string(data) != "updated" {
+		t.Fatal("source data error:", err, string(data))
+	}
+}
+
+func TestOpenReader(t *testing.T) {
+	resetSource(t)
+
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00")
+
+	block, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = SetSourceReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	if block, err = Lookup("config"); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf, err := io.ReadAll(block.NewReader()); err != nil || string(buf) != "data" {
+		t.Fatal("reader data error:", err, string(buf))
+	}
+
+	if _, err = block.Write([]byte("changed")); !errors.Is(err, ErrReadOnly) {
+		t.Fatal("write should fail:", err)
+	}
+
+	if err = Update(false, func() error { return nil }); !errors.Is(err, ErrReadOnly) {
+		t.Fatal("update should fail:", err)
+	}
+}
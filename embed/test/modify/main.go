@@ -1,33 +1,30 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"os"
+
+	"github.com/zooyer/golib/embed"
 )
 
+// 替换文件中唯一出现的字符串，替换内容较短时以NUL填充，见embed patch
 func main() {
 	if len(os.Args) != 4 {
-		_ = fmt.Sprintf("Usage: %s <file> <srouce_string> <target_string>\n", os.Args[0])
-		return
+		fmt.Printf("Usage: %s <file> <source_string> <target_string>\n", os.Args[0])
+		os.Exit(2)
 	}
 
-	data, err := os.ReadFile(os.Args[1])
+	emd, err := embed.Open(os.Args[1])
 	if err != nil {
 		panic(err)
 	}
+	defer emd.Close()
 
-	var index = bytes.Index(data, []byte(os.Args[2]))
-	if index == -1 {
-		fmt.Println(os.Args[2], "Not found")
-		return
-	}
-
-	copy(data[index:], os.Args[3])
-
-	if err = os.WriteFile(os.Args[1], data, 0600); err != nil {
-		panic(err)
+	offsets, err := emd.Patch([]byte(os.Args[2]), []byte(os.Args[3]), 1, true)
+	if err != nil {
+		fmt.Println(os.Args[2], err)
+		os.Exit(1)
 	}
 
-	fmt.Println("Replace", index, os.Args[2], "to", os.Args[3])
+	fmt.Println("Replace", offsets[0], os.Args[2], "to", os.Args[3])
 }
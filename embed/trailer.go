@@ -0,0 +1,229 @@
+package embed
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// 尾部：追加在文件末尾的数据段，每段数据之后紧跟固定长度的尾部描述，从文件末尾
+// 向前依次解析。各段按类型从大到小排列，类型小的段位于最后，修改时只需重写其后
+// 较小的段。
+const (
+	trailerMagic = "\uEEEE\u0054\u0052\uEEEE"
+	trailerSize  = 28
+)
+
+// 尾部类型
+const (
+	trailerIndex   uint32 = 1 // 块索引
+	trailerPayload uint32 = 2 // 追加的载荷
+)
+
+type trailer struct {
+	Magic     uint64 // 尾部标志
+	Kind      uint32 // 类型
+	Length    uint64 // 数据长度，数据紧邻在尾部之前
+	DataCRC32 uint32 // 数据CRC32
+	CRC32     uint32 // 尾部CRC32
+}
+
+func (t *trailer) Encode() (data []byte, err error) {
+	var buf = make([]byte, trailerSize)
+
+	n, err := binary.Encode(buf, binary.BigEndian, t)
+	if err != nil {
+		return
+	}
+
+	return buf[:n], nil
+}
+
+// checksum 计算尾部crc32
+func (t *trailer) checksum() (sum uint32, err error) {
+	var clone = *t
+
+	clone.CRC32 = 0
+
+	data, err := clone.Encode()
+	if err != nil {
+		return
+	}
+
+	return crc32.ChecksumIEEE(data), nil
+}
+
+// section 尾部中的一段数据
+type section struct {
+	kind   uint32
+	offset int64  // 数据偏移
+	length int64  // 数据长度
+	sum    uint32 // 数据crc32
+}
+
+// readTrailers 从文件末尾向前解析各段尾部，按文件中的顺序返回，content为尾部之前的文件长度
+func readTrailers(file storage) (sections []section, content int64, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	var (
+		buf = make([]byte, trailerSize)
+		end = info.Size()
+	)
+	for end >= trailerSize {
+		if _, err = file.ReadAt(buf, end-trailerSize); err != nil {
+			return
+		}
+
+		var t trailer
+		if _, err = binary.Decode(buf, binary.BigEndian, &t); err != nil {
+			return
+		}
+
+		// 校验magic及尾部crc32
+		if t.Magic != binary.BigEndian.Uint64([]byte(trailerMagic)) {
+			break
+		}
+
+		if sum, err := t.checksum(); err != nil || sum != t.CRC32 {
+			break
+		}
+
+		if t.Length > uint64(end-trailerSize) {
+			break
+		}
+
+		var offset = end - trailerSize - int64(t.Length)
+
+		sections = append([]section{{kind: t.Kind, offset: offset, length: int64(t.Length), sum: t.DataCRC32}}, sections...)
+		end = offset
+	}
+
+	return sections, end, nil
+}
+
+// readSection 读取并校验一段尾部数据
+func readSection(file storage, s section) (data []byte, err error) {
+	data = make([]byte, s.length)
+	if _, err = file.ReadAt(data, s.offset); err != nil {
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(data) != s.sum {
+		return nil, errors.New("invalid trailer checksum")
+	}
+
+	return
+}
+
+// writeTrailer 替换kind类型的尾部：保留原有数据的前keep字节并追加data，data为nil时
+// 删除该尾部。位于其后的较小类型的尾部读入内存后重新追加。
+func writeTrailer(file storage, kind uint32, keep int64, data io.Reader) (err error) {
+	sections, content, err := readTrailers(file)
+	if err != nil {
+		return
+	}
+
+	// 类型不大于kind的尾部均需重写
+	var (
+		index  = len(sections)
+		offset = content
+	)
+	for index > 0 && sections[index-1].kind <= kind {
+		index--
+	}
+
+	if index > 0 {
+		var s = sections[index-1]
+		offset = s.offset + s.length + trailerSize
+	}
+
+	var (
+		found bool
+		tails []section
+	)
+	for _, s := range sections[index:] {
+		if s.kind == kind {
+			found = true
+			continue
+		}
+
+		tails = append(tails, s)
+	}
+
+	if !found {
+		keep = 0
+	} else if keep > sections[index].length {
+		return errors.New("invalid trailer length")
+	}
+
+	// 暂存其后的尾部
+	var buffers = make([][]byte, len(tails))
+	for i, s := range tails {
+		if buffers[i], err = readSection(file, s); err != nil {
+			return
+		}
+	}
+
+	// 保留数据的crc32
+	var hash = crc32.NewIEEE()
+	if _, err = io.Copy(hash, io.NewSectionReader(file, offset, keep)); err != nil {
+		return
+	}
+
+	var end = offset
+	if data != nil {
+		if err = file.Truncate(offset + keep); err != nil {
+			return
+		}
+
+		var n int64
+		if n, err = io.Copy(io.NewOffsetWriter(file, offset+keep), io.TeeReader(data, hash)); err != nil {
+			return
+		}
+
+		if end, err = appendTrailer(file, offset+keep+n, trailer{Kind: kind, Length: uint64(keep + n), DataCRC32: hash.Sum32()}); err != nil {
+			return
+		}
+	} else if err = file.Truncate(offset); err != nil {
+		return
+	}
+
+	for i, s := range tails {
+		if _, err = file.WriteAt(buffers[i], end); err != nil {
+			return
+		}
+
+		if end, err = appendTrailer(file, end+s.length, trailer{Kind: s.kind, Length: uint64(s.length), DataCRC32: s.sum}); err != nil {
+			return
+		}
+	}
+
+	if err = file.Truncate(end); err != nil {
+		return
+	}
+
+	return file.Sync()
+}
+
+// appendTrailer 在offset处写入尾部，返回写入后的文件末尾
+func appendTrailer(file storage, offset int64, t trailer) (end int64, err error) {
+	t.Magic = binary.BigEndian.Uint64([]byte(trailerMagic))
+	if t.CRC32, err = t.checksum(); err != nil {
+		return
+	}
+
+	data, err := t.Encode()
+	if err != nil {
+		return
+	}
+
+	if _, err = file.WriteAt(data, offset); err != nil {
+		return
+	}
+
+	return offset + trailerSize, nil
+}
@@ -0,0 +1,143 @@
+package embed
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ErrNoMatch reports a block without a matching block in the other file.
+var ErrNoMatch = errors.New("no matching block")
+
+// BlockPair is a pair of matching blocks of two files, Old or New is -1
+// when the block has no match.
+type BlockPair struct {
+	Name string // 块名称，未命名的块为空
+	Old  int    // 旧文件中的块编号
+	New  int    // 新文件中的块编号
+}
+
+// PairBlocks matches the blocks of two files, named blocks by name and
+// unnamed blocks by position among the unnamed blocks. Pairs follow the
+// order of the new blocks, followed by the old blocks without a match.
+func PairBlocks(old, new []Block) (pairs []BlockPair) {
+	var (
+		names   = make(map[string]int)
+		unnamed []int
+		matched = make(map[int]bool)
+	)
+	for i, b := range old {
+		if b.name == "" {
+			unnamed = append(unnamed, i)
+		} else {
+			names[b.name] = i
+		}
+	}
+
+	var position int
+	for i, b := range new {
+		var pair = BlockPair{Name: b.name, Old: -1, New: i}
+		if b.name != "" {
+			if id, exists := names[b.name]; exists {
+				pair.Old = id
+			}
+		} else if position < len(unnamed) {
+			pair.Old = unnamed[position]
+			position++
+		}
+
+		if pair.Old != -1 {
+			matched[pair.Old] = true
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	for i, b := range old {
+		if !matched[i] {
+			pairs = append(pairs, BlockPair{Name: b.name, Old: i, New: -1})
+		}
+	}
+
+	return
+}
+
+// TransplantResult is the result of transplanting a pair of blocks.
+type TransplantResult struct {
+	BlockPair
+	Len uint32 // 复制的数据大小，跳过的空块为0
+	Err error  // 无匹配的块为ErrNoMatch
+}
+
+// Transplant copies the data of the blocks of old into the matching blocks
+// of e, see PairBlocks. The stored data is copied as it is, so compressed,
+// encrypted and signed data is kept without the keys. Empty blocks are
+// skipped, and blocks without a match or too large for the matching block
+// are reported in the results.
+func (e *Embed) Transplant(old *Embed) (results []TransplantResult, err error) {
+	olds, err := old.Blocks()
+	if err != nil {
+		return
+	}
+
+	news, err := e.Blocks()
+	if err != nil {
+		return
+	}
+
+	for _, pair := range PairBlocks(olds, news) {
+		var result = TransplantResult{BlockPair: pair}
+		if pair.Old == -1 || pair.New == -1 {
+			result.Err = ErrNoMatch
+		} else if olds[pair.Old].StoredLen() > 0 {
+			result.Len = olds[pair.Old].Len()
+			result.Err = news[pair.New].transplant(olds[pair.Old])
+		}
+
+		results = append(results, result)
+	}
+
+	return
+}
+
+// transplant 复制存储的数据及数据标志位，nonce按目标块存放在头中或数据之前
+func (b *Block) transplant(from Block) (err error) {
+	defer b.wlock()()
+
+	data, err := io.ReadAll(from.stored())
+	if err != nil {
+		return
+	}
+
+	if nonce := from.headerNonce(); nonce != nil {
+		data = append(nonce[:len(nonce):len(nonce)], data...)
+	}
+
+	ext, data, err := b.splitNonce(from.header.encryption(), data)
+	if err != nil {
+		return
+	}
+
+	// 校验数据大小
+	if uint32(len(data)) > b.cap() {
+		return fmt.Errorf("data too large: %d > %d", len(data), b.cap())
+	}
+
+	if err = b.checkAtomic(); err != nil {
+		return
+	}
+
+	if ext != nil {
+		if err = b.writeExtensions(ext); err != nil {
+			return
+		}
+	}
+
+	// 写入数据
+	if _, err = b.target().WriteAt(data, 0); err != nil {
+		return
+	}
+
+	return b.commitFlags(uint32(len(data)), crc32.ChecksumIEEE(data), from.len(), from.header.Flags&dataFlags)
+}
@@ -0,0 +1,70 @@
+package embed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEmbed_Transplant(t *testing.T) {
+	var (
+		old = openTestFile(t, Size1KB+NameTag+"config\x00", Size1KB+"1", Size2KB+"2", Size1KB+NameTag+"gone\x00")
+		emd = openTestFile(t, Size2KB+"x", Size1KB+"y", Size4KB+NameTag+"config\x00", Size1KB+NameTag+"new\x00")
+	)
+
+	blocks, err := old.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = blocks[0].SetCompression(CompressGzip)
+	for i, data := range []string{`{"port":8080}`, "first", strings.Repeat("x", 1500), "gone"} {
+		if _, err = blocks[i].Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := emd.Transplant(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []struct {
+		name     string
+		old, new int
+		ok       bool
+	}{
+		{"", 1, 0, true},
+		{"", 2, 1, false}, // 容量不足
+		{"config", 0, 2, true},
+		{"new", -1, 3, false},
+		{"gone", 3, -1, false},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatal("transplant results error:", results)
+	}
+
+	for i, e := range expected {
+		var r = results[i]
+		if r.Name != e.name || r.Old != e.old || r.New != e.new || (r.Err == nil) != e.ok {
+			t.Fatal("transplant result error:", i, r)
+		}
+	}
+
+	if !errors.Is(results[3].Err, ErrNoMatch) {
+		t.Fatal("unmatched block error:", results[3].Err)
+	}
+
+	if blocks, err = emd.Blocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	if readString(t, blocks[0]) != "first" || blocks[1].Len() != 0 {
+		t.Fatal("transplant unnamed blocks error")
+	}
+
+	if blocks[2].Compression() != CompressGzip || readString(t, blocks[2]) != `{"port":8080}` {
+		t.Fatal("transplant compressed block error:", blocks[2])
+	}
+}
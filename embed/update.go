@@ -0,0 +1,181 @@
+package embed
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+)
+
+// Update applies fn to a copy of the file and atomically replaces the file
+// with it, so the file is never modified in place. Blocks obtained from e
+// before the update refer to the replaced file and must be reloaded.
+func (e *Embed) Update(fn func(e *Embed) error) error {
+	return e.update(fn, nil)
+}
+
+// update 复制文件并在副本上执行fn，同步落盘后原子替换原文件，blocks在执行
+// 期间切换到副本，替换后切换到新文件
+func (e *Embed) update(fn func(e *Embed) error, blocks []*Block) (err error) {
+	if e.name == "" {
+		return ErrReadOnly
+	}
+
+	info, err := e.file.Stat()
+	if err != nil {
+		return
+	}
+
+	// 1. 在同目录创建副本，保证rename原子性
+	var dir, base = filepath.Split(e.name)
+	if dir == "" {
+		dir = "."
+	}
+
+	temp, err := os.CreateTemp(dir, "."+base+".embed-*")
+	if err != nil {
+		return
+	}
+
+	var (
+		files   = make([]storage, len(blocks))
+		headers = make([]Header, len(blocks))
+		chains  = make([][]Header, len(blocks))
+	)
+	for i, b := range blocks {
+		var unlock = b.wlock()
+		files[i], headers[i], chains[i] = b.file, b.header, b.chain
+		b.file = temp
+		unlock()
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		// 失败时还原
+		for i, b := range blocks {
+			var unlock = b.wlock()
+			b.file, b.header, b.chain = files[i], headers[i], chains[i]
+			unlock()
+		}
+
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+
+	if _, err = io.Copy(temp, io.NewSectionReader(e.file, 0, info.Size())); err != nil {
+		return
+	}
+
+	// 2. 保持属主及权限，chown会清除setuid位，需在chmod之前
+	if err = chown(temp, info); err != nil {
+		return
+	}
+
+	if err = temp.Chmod(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)); err != nil {
+		return
+	}
+
+	// 3. 修改副本
+	if err = fn(newEmbed(temp, temp.Name(), os.O_RDWR)); err != nil {
+		return
+	}
+
+	// 4. 同步落盘后替换原文件
+	if err = temp.Sync(); err != nil {
+		return
+	}
+
+	if err = os.Rename(temp.Name(), e.name); err != nil {
+		return
+	}
+
+	if err = syncDir(dir); err != nil {
+		return
+	}
+
+	// 5. 以原方式重新打开，关闭可写的副本，避免重新执行时text file busy，
+	// 持有文件锁时锁定新文件
+	file, err := os.OpenFile(e.name, e.flag, 0644)
+	if err != nil {
+		return
+	}
+
+	if e.flock != 0 {
+		if err = flock(file, e.flock); err != nil {
+			_ = file.Close()
+			return
+		}
+	}
+
+	for _, b := range blocks {
+		var unlock = b.wlock()
+		b.file = file
+		unlock()
+	}
+
+	e.lock.Lock()
+	_ = temp.Close()
+	_ = e.file.Close()
+	e.file, e.index = file, nil
+	e.mapping.replace(file, e.flag)
+	e.lock.Unlock()
+
+	return
+}
+
+// syncDir 同步目录，确保rename落盘
+func syncDir(dir string) (err error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+// Update persists changes into the running executable. Blocks allocated by
+// Malloc read and write a copy of the executable while fn runs, the copy
+// then replaces the executable and the blocks keep seeing the new data.
+// The process is re-executed with the same arguments when restart is set.
+func Update(restart bool, fn func() error) (err error) {
+	// 块链的各个size指向同一个块
+	mallocLock.Lock()
+	var blocks = make([]*Block, 0, len(malloc))
+	for _, b := range malloc {
+		if !slices.Contains(blocks, b) {
+			blocks = append(blocks, b)
+		}
+	}
+	mallocLock.Unlock()
+
+	emd, err := self()
+	if err != nil {
+		return
+	}
+
+	if err = emd.update(func(*Embed) error { return fn() }, blocks); err != nil {
+		return
+	}
+
+	if restart {
+		return Restart()
+	}
+
+	return
+}
+
+// Restart re-executes the running executable with the same arguments and
+// environment, it only returns on failure.
+func Restart() (err error) {
+	this, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	return syscall.Exec(this, os.Args, os.Environ())
+}
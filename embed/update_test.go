@@ -0,0 +1,71 @@
+package embed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbed_Update(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00")
+
+	if err := os.Chmod(emd.name, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.Stat(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := emd.Lookup("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 失败时不修改原文件
+	var failed = errors.New("failed")
+	err = emd.update(func(*Embed) error {
+		if _, err := block.Write([]byte("discarded")); err != nil {
+			return err
+		}
+
+		return failed
+	}, []*Block{block})
+	if !errors.Is(err, failed) || block.Len() != 0 {
+		t.Fatal("update failure error:", err, block.Len())
+	}
+
+	err = emd.update(func(*Embed) error {
+		_, err := block.Write([]byte("updated"))
+		return err
+	}, []*Block{block})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.Stat(emd.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if os.SameFile(before, after) || after.Mode() != before.Mode() {
+		t.Fatal("update replace file error:", after.Mode())
+	}
+
+	// 已分配的块及重新加载的块都能看到新数据
+	if readString(t, *block) != "updated" {
+		t.Fatal("update block error:", block)
+	}
+
+	if block, err = emd.Lookup("config"); err != nil || readString(t, *block) != "updated" {
+		t.Fatal("update reload error:", err)
+	}
+
+	// 不残留临时文件
+	entries, err := os.ReadDir(filepath.Dir(emd.name))
+	if err != nil || len(entries) != 1 {
+		t.Fatal("update temporary file left:", entries)
+	}
+}
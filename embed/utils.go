@@ -12,7 +12,7 @@ func byteString(b []byte) string {
 }
 
 func stringBytes(s string) []byte {
-	return *(*[]byte)(unsafe.Pointer(&s))
+	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
 
 func md5sum(data []byte) string {
@@ -40,3 +40,12 @@ func toUnicodeEscaped(s string) string {
 	}
 	return result
 }
+
+// errReaderAt 读取时始终返回错误
+type errReaderAt struct {
+	err error
+}
+
+func (r errReaderAt) ReadAt([]byte, int64) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,223 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+)
+
+// Encoding is the encoding of the value stored in a Var block.
+type Encoding uint8
+
+const (
+	EncodeJSON Encoding = iota // encoding/json
+	EncodeGob                  // encoding/gob
+)
+
+var encodings = map[Encoding]string{
+	EncodeJSON: "json",
+	EncodeGob:  "gob",
+}
+
+func (e Encoding) String() string {
+	if name, exists := encodings[e]; exists {
+		return name
+	}
+
+	return fmt.Sprintf("encoding(%d)", e)
+}
+
+// ParseEncoding parses the encoding name.
+func ParseEncoding(name string) (Encoding, error) {
+	for e, n := range encodings {
+		if n == name {
+			return e, nil
+		}
+	}
+
+	return EncodeJSON, fmt.Errorf("unknown encoding: %s", name)
+}
+
+func (e Encoding) marshal(v any) (data []byte, err error) {
+	switch e {
+	case EncodeJSON:
+		return json.Marshal(v)
+	case EncodeGob:
+		var buf bytes.Buffer
+		if err = gob.NewEncoder(&buf).Encode(v); err != nil {
+			return
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unknown encoding: %d", e)
+}
+
+func (e Encoding) unmarshal(data []byte, v any) error {
+	switch e {
+	case EncodeJSON:
+		return json.Unmarshal(data, v)
+	case EncodeGob:
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+	}
+
+	return fmt.Errorf("unknown encoding: %d", e)
+}
+
+// Var is a typed value stored in a block, configurable after the build
+// with 'embed set'. An empty block holds the compiled-in default.
+type Var[T any] struct {
+	block    *Block
+	value    T // 默认值
+	encoding Encoding
+	validate func(T) error
+}
+
+// NewVar returns a Var stored in block with the default value, encoded
+// with JSON.
+func NewVar[T any](block *Block, value T) *Var[T] {
+	return &Var[T]{block: block, value: value}
+}
+
+// MallocVar allocates the block reserved by size and returns a Var stored
+// in it, for example:
+//
+//	var config = embed.MustMallocVar(embed.Size4KB+embed.NameTag+"config\x00", Config{Port: 8080})
+func MallocVar[T any](size Size, value T) (_ *Var[T], err error) {
+	block, err := Malloc(size)
+	if err != nil {
+		return
+	}
+
+	return NewVar(block, value), nil
+}
+
+func MustMallocVar[T any](size Size, value T) *Var[T] {
+	v, err := MallocVar(size, value)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Block returns the block storing the value.
+func (v *Var[T]) Block() *Block {
+	return v.block
+}
+
+// Default returns the compiled-in default value.
+func (v *Var[T]) Default() T {
+	return v.value
+}
+
+// Encoding returns the encoding of the stored value.
+func (v *Var[T]) Encoding() Encoding {
+	return v.encoding
+}
+
+// SetEncoding sets the encoding of the stored value, which must match the
+// encoding of the value already stored. Values set by 'embed set' are JSON.
+func (v *Var[T]) SetEncoding(e Encoding) (err error) {
+	if _, exists := encodings[e]; !exists {
+		return fmt.Errorf("unknown encoding: %d", e)
+	}
+
+	v.encoding = e
+
+	return
+}
+
+// SetValidator sets the function checking values before Set stores them
+// and after Get reads them.
+func (v *Var[T]) SetValidator(validate func(T) error) {
+	v.validate = validate
+}
+
+func (v *Var[T]) check(value T) error {
+	if v.validate == nil {
+		return nil
+	}
+
+	return v.validate(value)
+}
+
+// Get returns the stored value, or the default when the block is empty.
+// The keys of a stored JSON object replace the same top-level fields of
+// the default, so the defaults of fields not set by 'embed set' are kept,
+// while a value stored by Set, holding every field, is returned as it was
+// stored. Other values are decoded into a zero value. If the stored value
+// can not be decoded or is invalid, the default is returned with the error.
+func (v *Var[T]) Get() (value T, err error) {
+	if v.block.Len() == 0 {
+		return v.value, nil
+	}
+
+	data, err := io.ReadAll(v.block.NewReader())
+	if err != nil {
+		return v.value, err
+	}
+
+	if data, err = v.overlay(data); err != nil {
+		return v.value, err
+	}
+
+	if err = v.encoding.unmarshal(data, &value); err != nil {
+		return v.value, fmt.Errorf("decode %s value: %w", v.encoding, err)
+	}
+
+	if err = v.check(value); err != nil {
+		return v.value, err
+	}
+
+	return
+}
+
+// overlay 将存储的JSON对象的键覆盖到默认值的同名字段上，字段名不区分大小写，
+// 与encoding/json一致；非JSON对象原样返回
+func (v *Var[T]) overlay(data []byte) (_ []byte, err error) {
+	var stored, merged map[string]json.RawMessage
+	if v.encoding != EncodeJSON || json.Unmarshal(data, &stored) != nil || stored == nil {
+		return data, nil
+	}
+
+	def, err := json.Marshal(v.value)
+	if err != nil {
+		return
+	}
+
+	if json.Unmarshal(def, &merged) != nil || merged == nil {
+		return data, nil
+	}
+
+	for key, value := range stored {
+		maps.DeleteFunc(merged, func(k string, _ json.RawMessage) bool {
+			return strings.EqualFold(k, key)
+		})
+
+		merged[key] = value
+	}
+
+	return json.Marshal(merged)
+}
+
+// Set validates and stores the value.
+func (v *Var[T]) Set(value T) (err error) {
+	if err = v.check(value); err != nil {
+		return
+	}
+
+	data, err := v.encoding.marshal(value)
+	if err != nil {
+		return
+	}
+
+	_, err = v.block.Write(data)
+
+	return
+}
@@ -0,0 +1,85 @@
+package embed
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testConfig struct {
+	Host  string
+	Port  int
+	Peers map[string]string
+}
+
+func TestVar(t *testing.T) {
+	var emd = openTestFile(t, Size1KB+NameTag+"config\x00", Size1KB+"2")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var def = testConfig{Host: "localhost", Port: 8080, Peers: map[string]string{"a": "1"}}
+	for i, encoding := range []Encoding{EncodeJSON, EncodeGob} {
+		var v = NewVar(&blocks[i], def)
+		if err = v.SetEncoding(encoding); err != nil {
+			t.Fatal(err)
+		}
+
+		v.SetValidator(func(c testConfig) error {
+			if c.Port <= 0 {
+				return errors.New("invalid port")
+			}
+
+			return nil
+		})
+
+		// 空块返回默认值
+		if value, err := v.Get(); err != nil || value.Port != 8080 {
+			t.Fatal("get default error:", err, value)
+		}
+
+		if err = v.Set(testConfig{Port: -1}); err == nil {
+			t.Fatal("set invalid value should fail")
+		}
+
+		var set = testConfig{Host: "example.com", Port: 9090, Peers: map[string]string{"b": "2"}}
+		if err = v.Set(set); err != nil {
+			t.Fatal(err)
+		}
+
+		value, err := v.Get()
+		if err != nil || !reflect.DeepEqual(value, set) {
+			t.Fatal("get error:", encoding, err, value)
+		}
+
+		if len(def.Peers) != 1 {
+			t.Fatal("default value modified:", def.Peers)
+		}
+	}
+
+	// embed set写入的部分字段覆盖默认值，其他字段保留默认值
+	if _, err = blocks[0].Write([]byte(`{"port":7070}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := NewVar(&blocks[0], def).Get()
+	if err != nil || !reflect.DeepEqual(value, testConfig{Host: "localhost", Port: 7070, Peers: map[string]string{"a": "1"}}) {
+		t.Fatal("get partial value error:", err, value)
+	}
+
+	// 返回的值不与默认值共用
+	if value.Peers["b"] = "2"; len(def.Peers) != 1 {
+		t.Fatal("default value modified:", def.Peers)
+	}
+
+	// 无法解码时返回默认值及错误
+	if _, err = blocks[0].Write([]byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err = NewVar(&blocks[0], def).Get(); err == nil || value.Port != 8080 {
+		t.Fatal("get invalid value should fail:", err, value)
+	}
+}
@@ -0,0 +1,120 @@
+package embed
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Writer streams data into a block, see Block.NewWriter.
+type Writer struct {
+	block   *Block
+	hash    hash.Hash32
+	encoder io.WriteCloser // 压缩编码，写入storedWriter或buffer
+	buffer  *bytes.Buffer  // 加密前的数据缓存
+	digest  hash.Hash      // 签名摘要
+	target  segments       // 写入区域
+	offset  int64          // 已存储的数据长度
+	size    int64          // 已写入的原始数据长度
+	err     error
+}
+
+// storedWriter 将压缩后的数据写入块
+type storedWriter struct {
+	w *Writer
+}
+
+func (s storedWriter) Write(data []byte) (n int, err error) {
+	var w = s.w
+
+	// 校验数据大小
+	if w.offset+int64(len(data)) > w.target.size() {
+		return 0, errors.New("data too large")
+	}
+
+	n, err = w.target.WriteAt(data, w.offset)
+
+	w.offset += int64(n)
+	w.hash.Write(data[:n])
+	if w.digest != nil {
+		w.digest.Write(data[:n])
+	}
+
+	return
+}
+
+func (w *Writer) Write(data []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	defer w.block.wlock()()
+
+	if n, err = w.encoder.Write(data); err != nil {
+		w.err = err
+	}
+
+	w.size += int64(n)
+
+	return
+}
+
+// Close commits the written data by updating the block header.
+func (w *Writer) Close() (err error) {
+	if w.err != nil {
+		return w.err
+	}
+
+	// 禁止重复提交
+	w.err = errors.New("writer already closed")
+
+	defer w.block.wlock()()
+
+	if err = w.encoder.Close(); err != nil {
+		return
+	}
+
+	// 加密后写入
+	if w.buffer != nil {
+		var data []byte
+		if data, err = w.block.encrypt.encrypt(w.block.key, w.buffer.Bytes(), dataHeader(uint32(w.size), w.block.flags())); err != nil {
+			return
+		}
+
+		// nonce移入头的扩展区，仍计入签名
+		var ext, stored []byte
+		if ext, stored, err = w.block.splitNonce(w.block.encrypt, data); err != nil {
+			return
+		}
+
+		if ext != nil {
+			if w.digest != nil {
+				w.digest.Write(data[:len(data)-len(stored)])
+			}
+
+			if err = w.block.writeExtensions(ext); err != nil {
+				return
+			}
+		}
+
+		if _, err = (storedWriter{w: w}).Write(stored); err != nil {
+			return
+		}
+	}
+
+	// 追加签名
+	if w.digest != nil {
+		var signature []byte
+		if signature, err = sign(w.block.signer, signedDigest(w.digest, uint32(w.size), w.block.flags())); err != nil {
+			return
+		}
+
+		w.digest = nil
+		if _, err = (storedWriter{w: w}).Write(signature); err != nil {
+			return
+		}
+	}
+
+	return w.block.commit(uint32(w.offset), w.hash.Sum32(), uint32(w.size))
+}
@@ -0,0 +1,52 @@
+package embed
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var emd = openTestFile(t, Size4KB+"1")
+
+	blocks, err := emd.Blocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		data   = bytes.Repeat([]byte("0123456789"), 300)
+		writer = blocks[0].NewWriter()
+	)
+
+	// 分块写入
+	if _, err = io.CopyBuffer(writer, bytes.NewReader(data), make([]byte, 7)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks[0].Len() != uint32(len(data)) {
+		t.Fatal("writer length error:", blocks[0].Len())
+	}
+
+	if blocks, err = emd.Blocks(); err != nil || len(blocks) != 1 {
+		t.Fatal("reload blocks error:", err)
+	}
+
+	result, err := io.ReadAll(blocks[0].NewReader())
+	if err != nil || !bytes.Equal(result, data) {
+		t.Fatal("writer data error:", err)
+	}
+
+	writer = blocks[0].NewWriter()
+	if _, err = writer.Write(make([]byte, 4097)); err == nil {
+		t.Fatal("write beyond capacity should fail")
+	}
+
+	if err = writer.Close(); err == nil {
+		t.Fatal("close after error should fail")
+	}
+}